@@ -0,0 +1,274 @@
+// Package accrual computes daily rebate and fee accruals of 1Source
+// contracts and builds monthly billing statements from them.
+package accrual
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dharm-kapadia/1source-go/collateral"
+	"github.com/dharm-kapadia/1source-go/models"
+)
+
+// DateLayout is the layout of the dates used by the 1Source REST API
+const DateLayout = "2006-01-02"
+
+// Accrual types. A rebate accrues on the collateral value of a cash
+// collateralized contract and is paid by the lender to the borrower. A
+// fee accrues on the contract value of a non-cash collateralized
+// contract and is paid by the borrower to the lender.
+const (
+	Rebate = "REBATE"
+	Fee    = "FEE"
+)
+
+// Accrual is the rebate or fee accrued by one contract on one day.
+// Rate is the annual rate in percent.
+type Accrual struct {
+	ContractId   string
+	Counterparty string
+	Date         time.Time
+	Type         string
+	Currency     string
+	Rate         float64
+	Basis        float64
+	Amount       float64
+}
+
+// position is the state of a contract from a given date onwards
+type position struct {
+	from       time.Time
+	quantity   uint32
+	basis      float64
+	accrueType string
+	closed     bool
+}
+
+// rate is the rebate or fee rate from a given date onwards
+type rate struct {
+	from  time.Time
+	value float64
+}
+
+// Compute returns the daily accruals of one contract between from and to,
+// both inclusive. history holds the versions of the contract, as returned
+// by the contract history endpoint, in any order; the last version is
+// taken as the current contract. Rerates take effect from their rate
+// effective date, returns and other changes from the date of the update.
+// self is the partyId used to name the counterparty.
+func Compute(history models.Contracts, from time.Time, to time.Time, self string) ([]Accrual, error) {
+	if len(history) == 0 {
+		return nil, errors.New("contract has no history")
+	}
+
+	versions := append(models.Contracts(nil), history...)
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].LastUpdateDateTime < versions[j].LastUpdateDateTime
+	})
+	current := versions[len(versions)-1]
+
+	start, err := startDate(current)
+	if err != nil {
+		return nil, err
+	}
+
+	positions, rates, err := timelines(versions)
+	if err != nil {
+		return nil, err
+	}
+
+	currency := current.Trade.BillingCurrency
+	dayCount := DayCountFor(currency)
+	counterparty := current.Counterparty(self)
+
+	if from.Before(start) {
+		from = start
+	}
+
+	var accruals []Accrual
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		p, ok := positionOn(positions, day)
+		if !ok || p.closed || p.quantity == 0 {
+			continue
+		}
+
+		r, ok := rateOn(rates, day)
+		if !ok {
+			continue
+		}
+
+		accruals = append(accruals, Accrual{
+			ContractId:   current.ContractId,
+			Counterparty: counterparty,
+			Date:         day,
+			Type:         p.accrueType,
+			Currency:     currency,
+			Rate:         r.value,
+			Basis:        p.basis,
+			Amount:       p.basis * r.value / 100 / dayCount.DaysPerYear,
+		})
+	}
+
+	return accruals, nil
+}
+
+// startDate returns the first accrual date of a contract: its settlement
+// date, or its trade date when no settlement date is known
+func startDate(c models.Contract) (time.Time, error) {
+	date := c.Trade.SettlementDate
+	if date == "" {
+		date = c.Trade.TradeDate
+	}
+
+	start, err := time.Parse(DateLayout, date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("contract %s: invalid start date '%s'", c.ContractId, date)
+	}
+
+	return start, nil
+}
+
+// timelines builds the position and rate timelines of a contract from its
+// versions, sorted by last update
+func timelines(versions models.Contracts) ([]position, []rate, error) {
+	var positions []position
+	var rates []rate
+
+	for i, v := range versions {
+		from, err := updateDate(v)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// The first version is in force from the start of the contract
+		if i == 0 {
+			from = time.Time{}
+		}
+
+		block := v.Trade.Collateral
+		p := position{
+			from:       from,
+			quantity:   v.Trade.Quantity,
+			basis:      block.ContractValue,
+			accrueType: Fee,
+			closed:     v.ContractStatus == models.ContractStatusClosed,
+		}
+		if block.Type == "CASH" {
+			p.basis = block.CollateralValue
+			p.accrueType = Rebate
+		}
+		positions = append(positions, p)
+
+		// Fees have their own rate, rebates a fixed rebate rate
+		fixed := v.Trade.Rate.Rebate.Fixed
+		if p.accrueType == Fee {
+			fixed = v.Trade.Rate.Fee
+		}
+
+		value := fixed.EffectiveRate
+		if value == 0 {
+			value = fixed.BaseRate
+		}
+
+		effective := from
+		if fixed.EffectiveDate != "" {
+			if effective, err = time.Parse(DateLayout, fixed.EffectiveDate); err != nil {
+				return nil, nil, fmt.Errorf("contract %s: invalid rate effectiveDate '%s'", v.ContractId, fixed.EffectiveDate)
+			}
+		}
+		if i == 0 {
+			effective = time.Time{}
+		}
+		rates = append(rates, rate{from: effective, value: value})
+	}
+
+	sort.SliceStable(positions, func(i, j int) bool { return positions[i].from.Before(positions[j].from) })
+	sort.SliceStable(rates, func(i, j int) bool { return rates[i].from.Before(rates[j].from) })
+
+	return positions, rates, nil
+}
+
+// updateDate returns the date of the last update of a contract version
+func updateDate(c models.Contract) (time.Time, error) {
+	if c.LastUpdateDateTime == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, c.LastUpdateDateTime)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("contract %s: invalid lastUpdateDateTime '%s'", c.ContractId, c.LastUpdateDateTime)
+	}
+
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+}
+
+// positionOn returns the position in force on day
+func positionOn(positions []position, day time.Time) (position, bool) {
+	var found position
+	ok := false
+
+	for _, p := range positions {
+		if p.from.After(day) {
+			break
+		}
+		found, ok = p, true
+	}
+
+	return found, ok
+}
+
+// rateOn returns the rate in force on day
+func rateOn(rates []rate, day time.Time) (rate, bool) {
+	var found rate
+	ok := false
+
+	for _, r := range rates {
+		if r.from.After(day) {
+			break
+		}
+		found, ok = r, true
+	}
+
+	return found, ok
+}
+
+// RoundAmount rounds an accrued amount to cents
+func RoundAmount(amount float64) float64 {
+	return collateral.RoundCents(amount)
+}
+
+// ParsePeriod parses an accrual period given as a month (YYYY-MM), a day
+// (YYYY-MM-DD) or an inclusive range of days (YYYY-MM-DD:YYYY-MM-DD)
+func ParsePeriod(period string) (time.Time, time.Time, error) {
+	if first, last, found := strings.Cut(period, ":"); found {
+		from, err := time.Parse(DateLayout, first)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid period start '%s'", first)
+		}
+
+		to, err := time.Parse(DateLayout, last)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid period end '%s'", last)
+		}
+
+		if to.Before(from) {
+			return time.Time{}, time.Time{}, fmt.Errorf("period end %s is before its start %s", last, first)
+		}
+
+		return from, to, nil
+	}
+
+	if month, err := time.Parse("2006-01", period); err == nil {
+		return month, month.AddDate(0, 1, -1), nil
+	}
+
+	day, err := time.Parse(DateLayout, period)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid period '%s', expected YYYY-MM, YYYY-MM-DD or YYYY-MM-DD:YYYY-MM-DD", period)
+	}
+
+	return day, day, nil
+}
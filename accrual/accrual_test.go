@@ -0,0 +1,199 @@
+package accrual
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dharm-kapadia/1source-go/models"
+)
+
+// date parses a test date
+func date(t *testing.T, value string) time.Time {
+	t.Helper()
+
+	d, err := time.Parse(DateLayout, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+// history decodes the versions of a contract
+func history(t *testing.T, versions string) models.Contracts {
+	t.Helper()
+
+	var contracts models.Contracts
+	if err := json.Unmarshal([]byte(versions), &contracts); err != nil {
+		t.Fatal(err)
+	}
+	return contracts
+}
+
+// near reports whether two amounts are equal to a millionth
+func near(a float64, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+const parties = `"transactingParties":[
+	{"partyRole":"LENDER","party":{"partyId":"TLEN-US"}},
+	{"partyRole":"BORROWER","party":{"partyId":"TBORR-US"}}]`
+
+func TestComputeLifecycle(t *testing.T) {
+	// Opened on 2024-01-30 at 5%, rerated to 4% from 2024-02-05, half
+	// returned on 2024-02-15 and closed on 2024-02-20. Versions are given
+	// out of order.
+	versions := history(t, `[
+		{"contractId":"c1","contractStatus":"OPEN","lastUpdateDateTime":"2024-02-15T16:30:00Z","trade":{
+			"quantity":500,"billingCurrency":"USD","settlementDate":"2024-01-30",
+			"rate":{"rebate":{"fixed":{"baseRate":5,"effectiveRate":4,"effectiveDate":"2024-02-05"}}},
+			"collateral":{"type":"CASH","contractValue":490000,"collateralValue":500000},`+parties+`}},
+		{"contractId":"c1","contractStatus":"OPEN","lastUpdateDateTime":"2024-01-29T09:00:00Z","trade":{
+			"quantity":1000,"billingCurrency":"USD","tradeDate":"2024-01-29","settlementDate":"2024-01-30",
+			"rate":{"rebate":{"fixed":{"baseRate":5}}},
+			"collateral":{"type":"CASH","contractValue":980000,"collateralValue":1000000},`+parties+`}},
+		{"contractId":"c1","contractStatus":"CLOSED","lastUpdateDateTime":"2024-02-20T10:00:00Z","trade":{
+			"quantity":500,"billingCurrency":"USD","settlementDate":"2024-01-30",
+			"rate":{"rebate":{"fixed":{"baseRate":5,"effectiveRate":4,"effectiveDate":"2024-02-05"}}},
+			"collateral":{"type":"CASH","contractValue":490000,"collateralValue":500000},`+parties+`}},
+		{"contractId":"c1","contractStatus":"OPEN","lastUpdateDateTime":"2024-02-10T12:00:00Z","trade":{
+			"quantity":1000,"billingCurrency":"USD","settlementDate":"2024-01-30",
+			"rate":{"rebate":{"fixed":{"baseRate":5,"effectiveRate":4,"effectiveDate":"2024-02-05"}}},
+			"collateral":{"type":"CASH","contractValue":980000,"collateralValue":1000000},`+parties+`}}
+	]`)
+
+	accruals, err := Compute(versions, date(t, "2024-01-01"), date(t, "2024-02-29"), "TLEN-US")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Accruals start on the settlement date and stop the day the contract
+	// is closed
+	if len(accruals) != 21 {
+		t.Fatalf("Compute() = %d accruals, expected 21", len(accruals))
+	}
+	if first, last := accruals[0].Date, accruals[len(accruals)-1].Date; !first.Equal(date(t, "2024-01-30")) || !last.Equal(date(t, "2024-02-19")) {
+		t.Errorf("Compute() accrued from %s to %s, expected 2024-01-30 to 2024-02-19", first.Format(DateLayout), last.Format(DateLayout))
+	}
+
+	// Rates are percentages, USD is ACT/360
+	expected := map[string]struct {
+		rate  float64
+		basis float64
+	}{
+		"2024-01-30": {5, 1000000},
+		"2024-02-04": {5, 1000000},
+		"2024-02-05": {4, 1000000},
+		"2024-02-14": {4, 1000000},
+		"2024-02-15": {4, 500000},
+		"2024-02-19": {4, 500000},
+	}
+	for _, a := range accruals {
+		e, found := expected[a.Date.Format(DateLayout)]
+		if !found {
+			continue
+		}
+
+		if a.Rate != e.rate || a.Basis != e.basis || !near(a.Amount, e.basis*e.rate/100/360) {
+			t.Errorf("accrual of %s = %v%% of %v: %v", a.Date.Format(DateLayout), a.Rate, a.Basis, a.Amount)
+		}
+		if a.ContractId != "c1" || a.Counterparty != "TBORR-US" || a.Type != Rebate || a.Currency != "USD" {
+			t.Errorf("accrual of %s = %+v", a.Date.Format(DateLayout), a)
+		}
+	}
+}
+
+func TestComputeDayCount(t *testing.T) {
+	tests := []struct {
+		currency string
+		days     float64
+	}{
+		{"USD", 360},
+		{"EUR", 360},
+		{"CHF", 360},
+		{"GBP", 365},
+		{"JPY", 365},
+		{"AUD", 365},
+		{"CAD", 365},
+		{"HKD", 365},
+		{"NZD", 365},
+		{"SGD", 365},
+		{"ZAR", 365},
+	}
+
+	for _, tt := range tests {
+		// A non-cash collateralized contract accrues a fee on its
+		// contract value, at its fee rate
+		versions := history(t, `[{"contractId":"c2","contractStatus":"OPEN","trade":{
+			"quantity":100,"billingCurrency":"`+tt.currency+`","settlementDate":"2024-03-01",
+			"rate":{"rebate":{"fixed":{"baseRate":5}},"fee":{"baseRate":0.25}},
+			"collateral":{"type":"NONCASH","contractValue":730000,"collateralValue":750000},`+parties+`}}]`)
+
+		accruals, err := Compute(versions, date(t, "2024-03-01"), date(t, "2024-03-01"), "TBORR-US")
+		if err != nil || len(accruals) != 1 {
+			t.Fatalf("Compute(%s) = %v, %v", tt.currency, accruals, err)
+		}
+
+		a := accruals[0]
+		if a.Type != Fee || a.Rate != 0.25 || a.Basis != 730000 || a.Counterparty != "TLEN-US" || !near(a.Amount, 730000*0.25/100/tt.days) {
+			t.Errorf("Compute(%s) = %+v, expected a fee on ACT/%v", tt.currency, a, tt.days)
+		}
+	}
+}
+
+func TestStatements(t *testing.T) {
+	jan31 := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	feb1 := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	accruals := []Accrual{
+		{ContractId: "c1", Counterparty: "B", Date: jan31, Type: Rebate, Currency: "USD", Amount: 0.004},
+		{ContractId: "c2", Counterparty: "B", Date: jan31, Type: Rebate, Currency: "USD", Amount: 0.004},
+		{ContractId: "c2", Counterparty: "B", Date: jan31.AddDate(0, 0, -1), Type: Rebate, Currency: "USD", Amount: 0.004},
+		{ContractId: "c1", Counterparty: "B", Date: feb1, Type: Rebate, Currency: "USD", Amount: 10},
+		{ContractId: "c3", Counterparty: "B", Date: jan31, Type: Fee, Currency: "USD", Amount: 1},
+		{ContractId: "c4", Counterparty: "B", Date: jan31, Type: Rebate, Currency: "EUR", Amount: 2},
+		{ContractId: "c5", Counterparty: "A", Date: feb1, Type: Rebate, Currency: "USD", Amount: 3},
+	}
+
+	// Daily amounts are summed before rounding, months end on the last
+	// day of the month
+	expected := []Statement{
+		{Counterparty: "A", Month: "2024-02", Currency: "USD", Type: Rebate, Contracts: 1, Days: 1, Amount: 3},
+		{Counterparty: "B", Month: "2024-01", Currency: "EUR", Type: Rebate, Contracts: 1, Days: 1, Amount: 2},
+		{Counterparty: "B", Month: "2024-01", Currency: "USD", Type: Fee, Contracts: 1, Days: 1, Amount: 1},
+		{Counterparty: "B", Month: "2024-01", Currency: "USD", Type: Rebate, Contracts: 2, Days: 3, Amount: 0.01},
+		{Counterparty: "B", Month: "2024-02", Currency: "USD", Type: Rebate, Contracts: 1, Days: 1, Amount: 10},
+	}
+
+	if statements := Statements(accruals); !reflect.DeepEqual(statements, expected) {
+		t.Errorf("Statements() = %+v, expected %+v", statements, expected)
+	}
+}
+
+func TestParsePeriod(t *testing.T) {
+	tests := []struct {
+		period string
+		from   string
+		to     string
+	}{
+		{"2024-02", "2024-02-01", "2024-02-29"},
+		{"2023-12", "2023-12-01", "2023-12-31"},
+		{"2024-02-10", "2024-02-10", "2024-02-10"},
+		{"2024-01-15:2024-02-14", "2024-01-15", "2024-02-14"},
+	}
+
+	for _, tt := range tests {
+		from, to, err := ParsePeriod(tt.period)
+		if err != nil || !from.Equal(date(t, tt.from)) || !to.Equal(date(t, tt.to)) {
+			t.Errorf("ParsePeriod(%q) = %v, %v, %v, expected %s to %s", tt.period, from, to, err, tt.from, tt.to)
+		}
+	}
+
+	for _, period := range []string{"2024-02-14:2024-01-15", "2024-13", "February", "2024-01-15:"} {
+		if _, _, err := ParsePeriod(period); err == nil {
+			t.Errorf("ParsePeriod(%q) succeeded", period)
+		}
+	}
+}
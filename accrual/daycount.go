@@ -0,0 +1,37 @@
+// Package accrual computes daily rebate and fee accruals of 1Source
+// contracts and builds monthly billing statements from them.
+package accrual
+
+// DayCount is a money market day-count convention
+type DayCount struct {
+	Name        string
+	DaysPerYear float64
+}
+
+// Day-count conventions used for billing currencies
+var (
+	Act360 = DayCount{Name: "ACT/360", DaysPerYear: 360}
+	Act365 = DayCount{Name: "ACT/365", DaysPerYear: 365}
+)
+
+// act365Currencies are the billing currencies whose money market
+// convention is ACT/365. All other currencies use ACT/360.
+var act365Currencies = map[string]bool{
+	"AUD": true,
+	"CAD": true,
+	"GBP": true,
+	"HKD": true,
+	"JPY": true,
+	"NZD": true,
+	"SGD": true,
+	"ZAR": true,
+}
+
+// DayCountFor returns the day-count convention of a billing currency
+func DayCountFor(currency string) DayCount {
+	if act365Currencies[currency] {
+		return Act365
+	}
+
+	return Act360
+}
@@ -0,0 +1,100 @@
+// Package accrual computes daily rebate and fee accruals of 1Source
+// contracts and builds monthly billing statements from them.
+package accrual
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// Statement is the monthly billing of one accrual type in one currency
+// with one counterparty. Month is formatted as YYYY-MM.
+type Statement struct {
+	Counterparty string
+	Month        string
+	Currency     string
+	Type         string
+	Contracts    int
+	Days         int
+	Amount       float64
+}
+
+// Statements groups daily accruals into monthly billing statements per
+// counterparty, currency and accrual type. Amounts are summed unrounded
+// and rounded to cents once per statement.
+func Statements(accruals []Accrual) []Statement {
+	type key struct{ counterparty, month, currency, accrueType string }
+
+	statements := make(map[key]*Statement)
+	contracts := make(map[key]map[string]bool)
+
+	for _, a := range accruals {
+		k := key{a.Counterparty, a.Date.Format("2006-01"), a.Currency, a.Type}
+
+		s, ok := statements[k]
+		if !ok {
+			s = &Statement{Counterparty: k.counterparty, Month: k.month, Currency: k.currency, Type: k.accrueType}
+			statements[k] = s
+			contracts[k] = make(map[string]bool)
+		}
+
+		s.Days++
+		s.Amount += a.Amount
+		contracts[k][a.ContractId] = true
+	}
+
+	result := make([]Statement, 0, len(statements))
+	for k, s := range statements {
+		s.Contracts = len(contracts[k])
+		s.Amount = RoundAmount(s.Amount)
+		result = append(result, *s)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Counterparty != b.Counterparty {
+			return a.Counterparty < b.Counterparty
+		}
+		if a.Month != b.Month {
+			return a.Month < b.Month
+		}
+		if a.Currency != b.Currency {
+			return a.Currency < b.Currency
+		}
+		return a.Type < b.Type
+	})
+
+	return result
+}
+
+// WriteCSV writes one CSV record per daily accrual, preceded by a header
+func WriteCSV(w io.Writer, accruals []Accrual) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{"contractId", "counterparty", "date", "type", "currency", "rate", "basis", "amount"}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, a := range accruals {
+		record := []string{
+			a.ContractId,
+			a.Counterparty,
+			a.Date.Format(DateLayout),
+			a.Type,
+			a.Currency,
+			strconv.FormatFloat(a.Rate, 'f', -1, 64),
+			strconv.FormatFloat(a.Basis, 'f', 2, 64),
+			strconv.FormatFloat(a.Amount, 'f', 6, 64),
+		}
+
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
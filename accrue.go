@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/dharm-kapadia/1source-go/accrual"
+	"github.com/dharm-kapadia/1source-go/api"
+	"github.com/dharm-kapadia/1source-go/batch"
+	"github.com/dharm-kapadia/1source-go/models"
+)
+
+// accrue computes the daily rebate and fee accruals of every open or
+// closed contract over the period, taking rerates and returns from the
+// contract history into account, and prints the monthly billing
+// statements per counterparty. The daily accruals are written to csvFile
+// when given. It fails when a contract could not be accrued, as the
+// statements are then incomplete.
+func accrue(period string, bearer string, self string, csvFile string) error {
+	from, to, err := accrual.ParsePeriod(period)
+	if err != nil {
+		return err
+	}
+
+	contracts, err := fetchContracts(bearer, api.ListFilter{})
+	if err != nil {
+		return err
+	}
+
+	var selected models.Contracts
+	for _, c := range contracts {
+		if c.ContractStatus == models.ContractStatusOpen || c.ContractStatus == models.ContractStatusClosed {
+			selected = append(selected, c)
+		}
+	}
+
+	var mu sync.Mutex
+	var accruals []accrual.Accrual
+	var failed []batch.Result
+
+	batch.Run(selected, defaultConcurrency, func(c models.Contract) batch.Result {
+		history, err := fetchHistory(c, bearer)
+		if err != nil {
+			return batch.NewResult(c.ContractId, c.ContractId, err)
+		}
+
+		daily, err := accrual.Compute(history, from, to, self)
+		if err == nil {
+			mu.Lock()
+			accruals = append(accruals, daily...)
+			mu.Unlock()
+		}
+
+		return batch.NewResult(c.ContractId, c.ContractId, err)
+	}, func(result batch.Result) {
+		if !result.Succeeded() {
+			failed = append(failed, result)
+		}
+	})
+
+	header := fmt.Sprintf("Billing statements %s to %s", from.Format(accrual.DateLayout), to.Format(accrual.DateLayout))
+	fmt.Println(header)
+	fmt.Println(strings.Repeat("=", len(header)))
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "COUNTERPARTY\tMONTH\tTYPE\tCCY\tCONTRACTS\tACCRUAL DAYS\tAMOUNT\t")
+	for _, s := range accrual.Statements(accruals) {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%d\t%.2f\t\n", s.Counterparty, s.Month, s.Type, s.Currency, s.Contracts, s.Days, s.Amount)
+	}
+	tw.Flush()
+
+	if len(failed) > 0 {
+		fmt.Printf("\nFailed %d contract(s), the statements are incomplete:\n", len(failed))
+		for _, result := range failed {
+			fmt.Printf("  %s: %s\n", result.ContractId, result.Error)
+		}
+	}
+
+	log.Printf("Computed %d daily accruals for %d contracts, failed %d\n", len(accruals), len(selected), len(failed))
+
+	if csvFile != "" {
+		sort.Slice(accruals, func(i, j int) bool {
+			if accruals[i].ContractId != accruals[j].ContractId {
+				return accruals[i].ContractId < accruals[j].ContractId
+			}
+			return accruals[i].Date.Before(accruals[j].Date)
+		})
+
+		file, err := os.Create(csvFile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		if err := accrual.WriteCSV(file, accruals); err != nil {
+			return err
+		}
+
+		fmt.Printf("\nDaily accruals written to '%s'\n", csvFile)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d contracts could not be accrued", len(failed), len(selected))
+	}
+
+	return nil
+}
+
+// fetchHistory retrieves the versions of a contract from the contract
+// history endpoint. The contract itself is used when the history is empty.
+func fetchHistory(c models.Contract, bearer string) (models.Contracts, error) {
+	endPoint := appConfig.Endpoints.Expand(models.ContractHistoryPath, c.ContractId)
+	data, err := api.GetEntity(endPoint, bearer, "1Source Contract History")
+	if err != nil {
+		return nil, err
+	}
+
+	var history models.Contracts
+	if err := json.Unmarshal([]byte(data), &history); err != nil {
+		return nil, fmt.Errorf("decoding contract history: %w", err)
+	}
+
+	if len(history) == 0 {
+		history = models.Contracts{c}
+	}
+
+	return history, nil
+}
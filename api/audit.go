@@ -0,0 +1,31 @@
+// Package api provides functions for HTTP verb access to 1Source REST API.
+package api
+
+// Actions of the calls made by Post, as passed in Call
+const (
+	ActionPropose = "proposing contract"
+	ActionCancel  = "canceling proposed contract"
+	ActionDecline = "declining proposed contract"
+)
+
+// Call describes a call which changes state in the 1Source REST API.
+// Status is 0 when no response was received.
+type Call struct {
+	Action   string
+	URL      string
+	Body     []byte
+	Status   int
+	Response []byte
+	Err      error
+}
+
+// AuditHook, when set, is called after every call made by Post. Dry runs
+// are not calls and are not passed to it.
+var AuditHook func(Call)
+
+// audit passes a call to AuditHook
+func audit(call Call) {
+	if AuditHook != nil {
+		AuditHook(call)
+	}
+}
@@ -3,10 +3,13 @@ package api
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 
 	"github.com/Nerzal/gocloak/v13"
 	"github.com/dharm-kapadia/1source-go/models"
+	"github.com/dharm-kapadia/1source-go/redact"
 )
 
 // GetAuthToken logs into KeyCloak using credentials from the configuration
@@ -17,8 +20,8 @@ func GetAuthToken(cfg *models.AppConfig) (*gocloak.JWT, error) {
 	var err error
 
 	// Log into KeyCloak to get Auth Token
-	log.Println("Logging into KeyCloak to get Auth Token")
-	client := gocloak.NewClient(cfg.General.Auth_URL)
+	slog.Info("Logging into KeyCloak to get Auth Token", "auth_url", cfg.General.Auth_URL, "realm", cfg.General.Realm_Name, "username", cfg.Authentication.Username)
+	client := newKeycloakClient(cfg.General.Auth_URL)
 	ctx := context.Background()
 
 	token, err = client.Login(
@@ -30,10 +33,43 @@ func GetAuthToken(cfg *models.AppConfig) (*gocloak.JWT, error) {
 		cfg.Authentication.Password)
 
 	if err != nil {
-		log.Panic("Error retrieving Auth token", err)
+		err = redact.Err(keycloakError(err, ErrAuth))
+		slog.Error("Error retrieving Auth token", "error", err)
 	} else {
-		log.Println("Successfully received Auth token")
+		// The tokens must never be logged or printed
+		redact.AddSecrets(token.AccessToken, token.RefreshToken, token.IDToken)
+		slog.Info("Successfully received Auth token")
 	}
 
 	return token, err
 }
+
+// CheckRealm checks that the KeyCloak server at authURL answers and
+// knows the realm, without logging in
+func CheckRealm(authURL string, realm string) error {
+	client := newKeycloakClient(authURL)
+	ctx := context.Background()
+
+	slog.Info("Checking KeyCloak realm", "auth_url", authURL, "realm", realm)
+	_, err := client.GetIssuer(ctx, realm)
+	if err != nil {
+		return redact.Err(keycloakError(err, nil))
+	}
+
+	return nil
+}
+
+// keycloakError wraps an error of the KeyCloak client with ErrUnreachable
+// when no answer was received, and with refused otherwise
+func keycloakError(err error, refused error) error {
+	var apiErr *gocloak.APIError
+	if errors.As(err, &apiErr) && apiErr.Code == 0 {
+		return fmt.Errorf("%w: %w", ErrUnreachable, err)
+	}
+
+	if refused == nil {
+		return err
+	}
+
+	return fmt.Errorf("%w: %w", refused, err)
+}
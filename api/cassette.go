@@ -0,0 +1,236 @@
+// Package api provides functions for HTTP verb access to 1Source REST API.
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/dharm-kapadia/1source-go/redact"
+)
+
+// Interaction is one HTTP call stored in a cassette file. A cassette is
+// an NDJSON file of interactions in the order they were made.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the request of an interaction, redacted
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is the response of an interaction, redacted
+type RecordedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Status     string      `json:"status"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// ErrNotRecorded is returned when replaying a call which the cassette
+// does not hold
+var ErrNotRecorded = errors.New("call not found in cassette")
+
+// Record makes Transport write every call, with credentials and sensitive
+// fields redacted, to the cassette file at path, which is truncated
+func Record(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Recording calls", "cassette", path)
+	Transport = &recorder{next: Transport, file: f}
+
+	return nil
+}
+
+// Replay makes Transport answer every call from the cassette file at
+// path, without using the network
+func Replay(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	interactions, err := ReadCassette(f)
+	if err != nil {
+		return fmt.Errorf("cassette '%s': %w", path, err)
+	}
+
+	slog.Info("Replaying calls", "cassette", path, "interactions", len(interactions))
+	Transport = &replayer{interactions: interactions, used: make([]bool, len(interactions))}
+
+	return nil
+}
+
+// ReadCassette returns the interactions of a cassette
+func ReadCassette(r io.Reader) ([]Interaction, error) {
+	var interactions []Interaction
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var interaction Interaction
+		if err := json.Unmarshal(scanner.Bytes(), &interaction); err != nil {
+			return interactions, fmt.Errorf("line %d: %w", line, err)
+		}
+		interactions = append(interactions, interaction)
+	}
+
+	return interactions, scanner.Err()
+}
+
+// recorder is a transport writing every call it passes on to a cassette
+type recorder struct {
+	next http.RoundTripper
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+func (rec *recorder) RoundTrip(request *http.Request) (*http.Response, error) {
+	var body []byte
+	if request.Body != nil {
+		var err error
+		if body, err = io.ReadAll(request.Body); err != nil {
+			return nil, err
+		}
+		request.Body.Close()
+		request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	response, err := rec.next.RoundTrip(request)
+	if err != nil {
+		// Nothing was answered, there is nothing to replay
+		return nil, err
+	}
+
+	responseBody, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	response.Body = io.NopCloser(bytes.NewReader(responseBody))
+
+	interaction := Interaction{
+		Request: RecordedRequest{
+			Method: request.Method,
+			URL:    redact.String(request.URL.String()),
+			Header: redactHeader(request.Header),
+			Body:   redact.String(string(body)),
+		},
+		Response: RecordedResponse{
+			StatusCode: response.StatusCode,
+			Status:     response.Status,
+			Header:     redactHeader(response.Header),
+			Body:       redact.String(string(responseBody)),
+		},
+	}
+
+	if err := rec.write(interaction); err != nil {
+		slog.Error("Error writing cassette", "file", rec.file.Name(), "error", err)
+	}
+
+	return response, nil
+}
+
+// write appends an interaction to the cassette
+func (rec *recorder) write(interaction Interaction) error {
+	var buf bytes.Buffer
+
+	// Keep <redacted> readable
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(interaction); err != nil {
+		return err
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	_, err := rec.file.Write(buf.Bytes())
+	return err
+}
+
+// replayer is a transport answering calls from recorded interactions.
+// A call is answered by the first interaction not yet used with the same
+// method and URL, so repeated calls get the responses in recorded order.
+type replayer struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+func (rep *replayer) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Body != nil {
+		request.Body.Close()
+	}
+
+	url := redact.String(request.URL.String())
+
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+
+	for i, interaction := range rep.interactions {
+		if rep.used[i] || interaction.Request.Method != request.Method || interaction.Request.URL != url {
+			continue
+		}
+		rep.used[i] = true
+
+		recorded := interaction.Response
+
+		// Redaction may have changed the length of the body
+		header := recorded.Header.Clone()
+		if header == nil {
+			header = make(http.Header)
+		}
+		header.Del("Content-Length")
+
+		return &http.Response{
+			StatusCode:    recorded.StatusCode,
+			Status:        recorded.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(bytes.NewReader([]byte(recorded.Body))),
+			ContentLength: int64(len(recorded.Body)),
+			Request:       request,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s %s", ErrNotRecorded, request.Method, url)
+}
+
+// redactHeader returns a copy of header safe to store
+func redactHeader(header http.Header) http.Header {
+	redacted := make(http.Header, len(header))
+	for name, values := range header {
+		for _, value := range values {
+			redacted.Add(name, redact.Header(name, value))
+		}
+	}
+
+	return redacted
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/dharm-kapadia/1source-go/models"
+	"github.com/dharm-kapadia/1source-go/redact"
+	"github.com/dharm-kapadia/1source-go/simulator"
+)
+
+// session logs into the simulator at url, proposes and cancels a
+// contract, and returns the responses received along the way
+func session(t *testing.T, url string) []string {
+	t.Helper()
+
+	var cfg models.AppConfig
+	cfg.General.Auth_URL = url + "/auth"
+	cfg.General.Realm_Name = simulator.Realm
+	cfg.Authentication.Username = "TestLender1User"
+	cfg.Authentication.Password = "password"
+
+	token, err := GetAuthToken(&cfg)
+	if err != nil {
+		t.Fatalf("GetAuthToken() error = %v", err)
+	}
+	bearer := "Bearer " + token.AccessToken
+
+	proposal, err := os.ReadFile("../proposed_trade.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contracts := url + simulator.LedgerPath + "contracts"
+	cir, err := ProposeContract(contracts, bearer, proposal)
+	if err != nil {
+		t.Fatalf("ProposeContract() error = %v", err)
+	}
+
+	// The contract is read before and after the cancel, as -cc does
+	before, err := Get(contracts+"/"+cir.ContractId(), bearer)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	canceled, err := PostCancelContract(contracts+"/"+cir.ContractId()+"/cancel", bearer)
+	if err != nil {
+		t.Fatalf("PostCancelContract() error = %v", err)
+	}
+	after, err := Get(contracts+"/"+cir.ContractId(), bearer)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	return []string{cir.ResourceUri, before, canceled, after}
+}
+
+// accessToken matches the access token of a login response in a cassette
+var accessToken = regexp.MustCompile(`access_token\\":\\"([^\\]*)`)
+
+func TestRecordReplay(t *testing.T) {
+	defer func(transport http.RoundTripper) { Transport = transport }(Transport)
+
+	cassette := filepath.Join(t.TempDir(), "cassette.ndjson")
+
+	_, server := simulator.NewServer()
+	if err := Record(cassette); err != nil {
+		t.Fatal(err)
+	}
+	recorded := session(t, server.URL)
+	server.Close()
+
+	b, err := os.ReadFile(cassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "password=password") {
+		t.Error("cassette leaks the password")
+	}
+	for _, m := range accessToken.FindAllStringSubmatch(string(b), -1) {
+		if m[1] != redact.Redacted {
+			t.Errorf("cassette leaks the access token %q", m[1])
+		}
+	}
+	if !strings.Contains(string(b), "Bearer "+redact.Redacted) {
+		t.Error("cassette does not hold the redacted Authorization header")
+	}
+
+	// The server is gone, every answer comes from the cassette
+	if err := Replay(cassette); err != nil {
+		t.Fatal(err)
+	}
+	replayed := session(t, server.URL)
+
+	// Sensitive fields were redacted in the cassette
+	for i := range recorded {
+		if replayed[i] != redact.String(recorded[i]) {
+			t.Errorf("replayed response %d = %s, recorded %s", i+1, replayed[i], recorded[i])
+		}
+	}
+
+	if _, err := Get(server.URL+simulator.LedgerPath+"parties", "Bearer x"); !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("Get() of a call not recorded error = %v, expected ErrNotRecorded", err)
+	}
+}
@@ -0,0 +1,55 @@
+// Package api provides functions for HTTP verb access to 1Source REST API.
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/dharm-kapadia/1source-go/redact"
+)
+
+var (
+	// DryRun makes Post write the request it would send to DryRunOutput
+	// instead of calling the 1Source REST API
+	DryRun bool
+
+	// DryRunOutput is where dry-run requests are written
+	DryRunOutput io.Writer = os.Stdout
+
+	// ErrDryRun is returned by Post when DryRun is set
+	ErrDryRun = errors.New("dry run, request not sent")
+)
+
+// WriteDryRun writes the HTTP method, URL, headers and body of a request
+// to w, with credentials and sensitive fields redacted
+func WriteDryRun(w io.Writer, request *http.Request, body []byte) error {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("%s %s\n", request.Method, redact.String(request.URL.String())))
+
+	names := make([]string, 0, len(request.Header))
+	for name := range request.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, value := range request.Header[name] {
+			sb.WriteString(fmt.Sprintf("%s: %s\n", name, redact.Header(name, value)))
+		}
+	}
+
+	sb.WriteString("\n")
+	if len(body) > 0 {
+		sb.Write(redact.Bytes(body))
+		sb.WriteString("\n")
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
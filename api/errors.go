@@ -0,0 +1,27 @@
+// Package api provides functions for HTTP verb access to 1Source REST API.
+package api
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrAuth is wrapped by the errors of GetAuthToken when KeyCloak refuses
+// the login
+var ErrAuth = errors.New("authentication failed")
+
+// ErrUnreachable is wrapped by the errors of GetAuthToken and CheckRealm
+// when no answer is received from KeyCloak
+var ErrUnreachable = errors.New("KeyCloak cannot be reached")
+
+// StatusError is returned when the 1Source REST API answers with an
+// unexpected HTTP status
+type StatusError struct {
+	Action     string
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("error %s, HTTP Response Status: %s", e.Action, e.Status)
+}
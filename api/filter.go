@@ -0,0 +1,156 @@
+// Package api provides functions for HTTP verb access to 1Source REST API.
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Query parameters of the list filters of the 1Source REST API
+const (
+	SinceParam     = "since"
+	BeforeParam    = "before"
+	EventTypeParam = "eventType"
+	StatusParam    = "status"
+	PartyParam     = "partyId"
+	TickerParam    = "ticker"
+	IsinParam      = "isin"
+)
+
+// ListFilter selects the entities returned by a list endpoint, on the
+// server side. Empty fields are not sent.
+type ListFilter struct {
+	// Since and Before bound the date and time of the entities, as
+	// YYYY-MM-DD or RFC 3339. Since is inclusive, Before exclusive.
+	Since  string
+	Before string
+
+	// EventType selects events of one type, such as CONTRACT_PROPOSED
+	EventType string
+
+	// Status selects entities in one status, such as OPEN
+	Status string
+
+	// Party, Ticker and Isin select entities by transacting party and
+	// instrument
+	Party  string
+	Ticker string
+	Isin   string
+}
+
+// filterEntities lists the filters supported by each entity type
+var filterEntities = map[string][]string{
+	"events":     {SinceParam, BeforeParam, EventTypeParam},
+	"contracts":  {SinceParam, BeforeParam, StatusParam, PartyParam, TickerParam, IsinParam},
+	"agreements": {SinceParam, BeforeParam, StatusParam, PartyParam, TickerParam, IsinParam},
+	"rerates":    {SinceParam, BeforeParam, StatusParam, PartyParam, TickerParam, IsinParam},
+	"returns":    {SinceParam, BeforeParam, StatusParam, PartyParam, TickerParam, IsinParam},
+	"recalls":    {SinceParam, BeforeParam, StatusParam, PartyParam, TickerParam, IsinParam},
+	"buyins":     {SinceParam, BeforeParam, StatusParam, PartyParam, TickerParam, IsinParam},
+}
+
+// IsEmpty reports whether no filter field is set
+func (f ListFilter) IsEmpty() bool {
+	return f == ListFilter{}
+}
+
+// Query returns the query parameters of the set filter fields
+func (f ListFilter) Query() url.Values {
+	query := url.Values{}
+
+	for _, field := range f.fields() {
+		if field.value != "" {
+			query.Set(field.name, field.value)
+		}
+	}
+
+	return query
+}
+
+// filterField is a filter field and its query parameter
+type filterField struct {
+	name  string
+	value string
+}
+
+// fields returns the filter fields in a fixed order
+func (f ListFilter) fields() []filterField {
+	return []filterField{
+		{SinceParam, f.Since},
+		{BeforeParam, f.Before},
+		{EventTypeParam, strings.ToUpper(f.EventType)},
+		{StatusParam, strings.ToUpper(f.Status)},
+		{PartyParam, f.Party},
+		{TickerParam, strings.ToUpper(f.Ticker)},
+		{IsinParam, strings.ToUpper(f.Isin)},
+	}
+}
+
+// Validate checks that the set filter fields are supported by the entity
+// type and that the dates are valid
+func (f ListFilter) Validate(entity string) error {
+	supported, found := filterEntities[entity]
+
+	for _, field := range f.fields() {
+		if field.value != "" && (!found || !slices.Contains(supported, field.name)) {
+			return fmt.Errorf("%s cannot be filtered by %s", entity, field.name)
+		}
+	}
+
+	var since, before time.Time
+	var err error
+
+	if f.Since != "" {
+		if since, err = ParseFilterTime(f.Since); err != nil {
+			return fmt.Errorf("invalid since '%s': %w", f.Since, err)
+		}
+	}
+	if f.Before != "" {
+		if before, err = ParseFilterTime(f.Before); err != nil {
+			return fmt.Errorf("invalid before '%s': %w", f.Before, err)
+		}
+	}
+	if f.Since != "" && f.Before != "" && !since.Before(before) {
+		return fmt.Errorf("since '%s' is not before '%s'", f.Since, f.Before)
+	}
+
+	return nil
+}
+
+// URL returns endPoint with the query parameters of the filter added
+func (f ListFilter) URL(endPoint string) (string, error) {
+	if f.IsEmpty() {
+		return endPoint, nil
+	}
+
+	u, err := url.Parse(endPoint)
+	if err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+	for name, values := range f.Query() {
+		query[name] = values
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+// ParseFilterTime parses a since or before value, a date as YYYY-MM-DD
+// (midnight UTC) or a date and time as RFC 3339
+func ParseFilterTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return t, fmt.Errorf("expected YYYY-MM-DD or an RFC 3339 date and time")
+	}
+
+	return t, nil
+}
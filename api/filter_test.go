@@ -0,0 +1,85 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/dharm-kapadia/1source-go/simulator"
+)
+
+func TestListFilterURL(t *testing.T) {
+	filter := ListFilter{Since: "2024-01-01", Status: "open", Party: "TLEN-US", Isin: "us46625h1005"}
+
+	got, err := filter.URL("https://api.example.com/v1/ledger/contracts?size=10")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "https://api.example.com/v1/ledger/contracts?isin=US46625H1005&partyId=TLEN-US&since=2024-01-01&size=10&status=OPEN"
+	if got != expected {
+		t.Errorf("URL() = %s, expected %s", got, expected)
+	}
+}
+
+func TestListFilterValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		entity string
+		filter ListFilter
+		valid  bool
+	}{
+		{"event type", "events", ListFilter{EventType: "CONTRACT_PROPOSED", Since: "2024-01-01T10:00:00Z"}, true},
+		{"contract fields", "contracts", ListFilter{Status: "OPEN", Party: "TLEN-US", Ticker: "IBM", Isin: "US4592001014"}, true},
+		{"event type of contracts", "contracts", ListFilter{EventType: "CONTRACT_PROPOSED"}, false},
+		{"status of events", "events", ListFilter{Status: "OPEN"}, false},
+		{"parties", "parties", ListFilter{Party: "TLEN-US"}, false},
+		{"invalid date", "events", ListFilter{Since: "01/02/2024"}, false},
+		{"empty range", "events", ListFilter{Since: "2024-02-01", Before: "2024-01-01"}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.filter.Validate(tc.entity)
+			if (err == nil) != tc.valid {
+				t.Errorf("Validate(%s) = %v, expected valid %v", tc.entity, err, tc.valid)
+			}
+		})
+	}
+}
+
+func TestListFilterSimulator(t *testing.T) {
+	sim, server := simulator.NewServer()
+	defer server.Close()
+
+	proposal := []byte(`{"trade":{"instrument":{"ticker":"IBM","isin":"US4592001014"},"transactingParties":[` +
+		`{"partyRole":"LENDER","party":{"partyId":"TLEN-US"}},` +
+		`{"partyRole":"BORROWER","party":{"partyId":"TBORR-US"}}]}}`)
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		id, err := sim.Propose("TLEN-US", proposal)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	if err := sim.Cancel("TLEN-US", ids[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	access, _, err := sim.Login("TestLender1User", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	endPoint, err := ListFilter{Status: "proposed", Ticker: "ibm"}.URL(server.URL + simulator.LedgerPath + "contracts")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ListEntity(endPoint, "Bearer "+access, "1Source Contracts", PageOptions{Size: 1})
+	if err != nil {
+		t.Fatalf("ListEntity() error = %v", err)
+	}
+
+	got := contractIds(t, data)
+	if len(got) != 2 || got[0] != ids[0] || got[1] != ids[2] {
+		t.Errorf("filtered contracts = %v, expected %v", got, []string{ids[0], ids[2]})
+	}
+}
@@ -3,12 +3,16 @@ package api
 
 import (
 	"context"
+	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
-	"net/url"
+	neturl "net/url"
 	"strings"
 	"time"
+
+	"github.com/dharm-kapadia/1source-go/logging"
+	"github.com/dharm-kapadia/1source-go/redact"
 )
 
 // Get performs an HTTP GET operation on the 1Source REST API
@@ -17,64 +21,150 @@ import (
 // entities based on an Id
 // It returns the entities from the query and any error encountered.
 func Get(apiEndPoint string, bearer string) (string, error) {
-	ctx := context.Background()
-	transport := &http.Transport{
-		Proxy: func(r *http.Request) (*url.URL, error) {
-			r.Header.Set("Authorization", bearer)
-			return nil, nil
-		},
+	response, logger, err := get(apiEndPoint, bearer)
+	if err != nil {
+		return "", err
+	}
+	defer closeBody(response, logger)
+
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		logger.Error("Error reading HTTP GET response", "error", err)
+		return "", redact.Err(err)
 	}
 
-	client := &http.Client{
-		Transport: transport,
-		Timeout:   time.Duration(10) * time.Second,
+	// Redacting a large body costs memory, only do it when it is logged
+	if logger.Enabled(context.Background(), slog.LevelDebug) {
+		logger.Debug("API response body", "body", redact.String(string(data)))
 	}
 
+	return string(data), nil
+}
+
+// get sends an HTTP GET to the 1Source REST API and returns the response
+// when its status is 200 OK. The caller closes the response body.
+func get(apiEndPoint string, bearer string) (*http.Response, *slog.Logger, error) {
+	// Responses are streamed, possibly for a long time: the client has no
+	// overall timeout, the wait for the headers and every read of the body
+	// are bounded instead
+	ctx, cancel := context.WithCancel(context.Background())
+	requestId := logging.NewRequestId()
+	logger := slog.With("request_id", requestId)
+
+	client := newClient(bearer, 0)
+
 	request, err := http.NewRequestWithContext(ctx, "GET", apiEndPoint, nil)
-	request.Header.Set("Authorization", bearer)
 
 	if err != nil {
-		log.Println("Error creating new HTTP Request: ", err)
-		return "", err
+		cancel()
+		logger.Error("Error creating new HTTP Request", "error", err)
+		return nil, logger, redact.Err(err)
 	}
 
-	log.Println("Calling API endpoint: ", apiEndPoint)
-	response, err := client.Do(request)
+	request.Header.Set("Authorization", bearer)
+	request.Header.Set("X-Request-ID", requestId)
 
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			log.Println("Error closing Body.\n[ERR] -", err)
-		}
-	}(response.Body)
+	logger.Info("Calling API endpoint", "method", "GET", "url", apiEndPoint)
+	start := time.Now()
+	timer := time.AfterFunc(ResponseTimeout, cancel)
+	response, err := client.Do(request)
+	timer.Stop()
 
 	if err != nil {
-		log.Println("Error in response.\n[ERR] -", err)
-	} else {
-		if response.StatusCode != http.StatusOK {
-			log.Println("Error in response status. [ERR] -", response.StatusCode)
-		} else {
-			data, _ := io.ReadAll(response.Body)
-			return string(data), err
+		if ctx.Err() != nil {
+			err = &timeoutError{fmt.Sprintf("no response from %s within %s", apiEndPoint, ResponseTimeout)}
 		}
+		cancel()
+		logger.Error("Error in response", "error", err)
+		return nil, logger, redact.Err(err)
 	}
 
-	return "", err
+	response.Body = &idleReader{
+		body:    response.Body,
+		timeout: IdleTimeout,
+		timer:   time.AfterFunc(IdleTimeout, cancel),
+		ctx:     ctx,
+		cancel:  cancel,
+		url:     apiEndPoint,
+	}
+
+	logger.Info("API response", "status", response.StatusCode, "elapsed", time.Since(start))
+
+	if response.StatusCode != http.StatusOK {
+		closeBody(response, logger)
+		logger.Error("Error in response status", "status", response.StatusCode)
+		return nil, logger, redact.Err(&StatusError{Action: "getting " + apiEndPoint, StatusCode: response.StatusCode, Status: response.Status})
+	}
+
+	return response, logger, nil
+}
+
+// idleReader is the body of a streamed response. A read waiting longer
+// than timeout for data cancels the request and fails.
+type idleReader struct {
+	body    io.ReadCloser
+	timeout time.Duration
+	timer   *time.Timer
+	ctx     context.Context
+	cancel  context.CancelFunc
+	url     string
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	// Only the time spent waiting for data counts, not the time the
+	// caller spends on what it read
+	r.timer.Reset(r.timeout)
+	n, err := r.body.Read(p)
+	r.timer.Stop()
+
+	if err != nil && r.ctx.Err() != nil {
+		err = &timeoutError{fmt.Sprintf("no data from %s for %s", r.url, r.timeout)}
+	}
+
+	return n, err
+}
+
+func (r *idleReader) Close() error {
+	r.timer.Stop()
+	r.cancel()
+	return r.body.Close()
+}
+
+// timeoutError is returned when the 1Source REST API stops answering. It
+// is a net.Error, as other network failures are.
+type timeoutError struct {
+	message string
+}
+
+func (e *timeoutError) Error() string {
+	return e.message
+}
+
+func (e *timeoutError) Timeout() bool {
+	return true
+}
+
+func (e *timeoutError) Temporary() bool {
+	return false
+}
+
+// closeBody closes the body of a response
+func closeBody(response *http.Response, logger *slog.Logger) {
+	if err := response.Body.Close(); err != nil {
+		logger.Warn("Error closing Body", "error", err)
+	}
 }
 
 // GetEntityById is a helper function to perform an HTTP GET to
-// retrieve a particular entity by Id from the 1Source REST API
+// retrieve a particular entity by Id from the 1Source REST API. The id is
+// escaped for use in a URL path.
 func GetEntityById(endPoint string, entity string, bearer string, header string) (string, error) {
-	url := endPoint + "/" + entity
-	entity, err := Get(url, bearer)
+	url := strings.TrimRight(endPoint, "/") + "/" + neturl.PathEscape(entity)
+	data, err := Get(url, bearer)
 	if err == nil {
-		log.Println(header)
-		log.Println(strings.Repeat("=", len(header)))
-		log.Println(entity)
-
-		return entity, err
+		return data, err
 	} else {
-		log.Printf("Error GET %s by id [%s]: %s", header, entity, err)
+		slog.Error("Error GET entity by id", "entity", header, "id", entity, "error", err)
 
 		return "", err
 	}
@@ -85,13 +175,9 @@ func GetEntityById(endPoint string, entity string, bearer string, header string)
 func GetEntity(endPoint string, bearer string, header string) (string, error) {
 	entity, err := Get(endPoint, bearer)
 	if err == nil {
-		log.Println(header)
-		log.Println(strings.Repeat("=", len(header)))
-		log.Println(entity)
-
 		return entity, err
 	} else {
-		log.Printf("Error GET %s: %s", header, err)
+		slog.Error("Error GET entity", "entity", header, "error", err)
 
 		return "", err
 	}
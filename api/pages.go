@@ -0,0 +1,234 @@
+// Package api provides functions for HTTP verb access to 1Source REST API.
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Query parameters of the paged list endpoints of the 1Source REST API.
+// Pages are numbered from 0.
+const (
+	PageParam = "page"
+	SizeParam = "size"
+)
+
+// DefaultPageSize is the number of entities requested per page
+const DefaultPageSize = 100
+
+// PageOptions controls how a list endpoint is read
+type PageOptions struct {
+	// Size is the number of entities per page, DefaultPageSize when 0
+	Size int
+
+	// Limit is the maximum number of entities read, all when 0
+	Limit int
+
+	// Prefetch is the number of pages requested ahead of the page being
+	// read, in parallel. Pages are still returned in order.
+	Prefetch int
+}
+
+// Pager reads a list endpoint page by page, fetching pages lazily:
+//
+//	pager := api.NewPager(endPoint, bearer, api.PageOptions{Limit: 1000})
+//	for pager.Next() {
+//		for _, entity := range pager.Page() {
+//			...
+//		}
+//	}
+//	if err := pager.Err(); err != nil {
+//		...
+//	}
+type Pager struct {
+	endPoint string
+	bearer   string
+	opts     PageOptions
+
+	next      int // number of the next page returned
+	requested int // number of the next page requested
+	pending   map[int]chan pageResult
+
+	page  []json.RawMessage
+	last  [sha256.Size]byte // checksum of the previous page
+	count int
+	done  bool
+	err   error
+}
+
+// pageResult is a fetched page
+type pageResult struct {
+	entities []json.RawMessage
+	err      error
+}
+
+// NewPager returns a pager over the list endpoint endPoint
+func NewPager(endPoint string, bearer string, opts PageOptions) *Pager {
+	if opts.Size <= 0 {
+		opts.Size = DefaultPageSize
+	}
+	if opts.Limit > 0 && opts.Limit < opts.Size {
+		opts.Size = opts.Limit
+	}
+	if opts.Prefetch < 0 {
+		opts.Prefetch = 0
+	}
+
+	return &Pager{
+		endPoint: endPoint,
+		bearer:   bearer,
+		opts:     opts,
+		pending:  make(map[int]chan pageResult),
+	}
+}
+
+// Next fetches the next page, reporting whether there is one. It
+// returns false at the end of the list, at the limit or on error.
+func (p *Pager) Next() bool {
+	if p.done {
+		p.page = nil
+		return false
+	}
+
+	// Keep Prefetch pages requested ahead of this one
+	for p.requested <= p.next+p.opts.Prefetch {
+		p.request(p.requested)
+		p.requested++
+	}
+
+	result := <-p.pending[p.next]
+	delete(p.pending, p.next)
+	p.next++
+
+	if result.err != nil {
+		p.err, p.done, p.page = result.err, true, nil
+		return false
+	}
+
+	page := result.entities
+
+	// An endpoint ignoring the paging parameters answers the same page
+	// again, which ends the list
+	sum := pageSum(page)
+	if p.next > 1 && sum == p.last {
+		p.done, p.page = true, nil
+		return false
+	}
+	p.last = sum
+
+	// A short page is the last one. A page longer than requested means
+	// the endpoint does not page and returned everything.
+	if len(page) != p.opts.Size {
+		p.done = true
+	}
+
+	if p.opts.Limit > 0 && p.count+len(page) >= p.opts.Limit {
+		page = page[:p.opts.Limit-p.count]
+		p.done = true
+	}
+
+	if len(page) == 0 {
+		p.done, p.page = true, nil
+		return false
+	}
+
+	p.count += len(page)
+	p.page = page
+
+	return true
+}
+
+// Page returns the entities of the current page
+func (p *Pager) Page() []json.RawMessage {
+	return p.page
+}
+
+// Err returns the error which stopped the pager, if any
+func (p *Pager) Err() error {
+	return p.err
+}
+
+// pageSum returns the checksum of the entities of a page
+func pageSum(page []json.RawMessage) [sha256.Size]byte {
+	h := sha256.New()
+	for _, entity := range page {
+		h.Write(entity)
+		h.Write([]byte{'\n'})
+	}
+
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// request starts fetching a page
+func (p *Pager) request(number int) {
+	result := make(chan pageResult, 1)
+	p.pending[number] = result
+
+	go func() {
+		entities, err := GetPage(p.endPoint, p.bearer, number, p.opts.Size)
+		result <- pageResult{entities, err}
+	}()
+}
+
+// GetPage performs an HTTP GET of one page of a list endpoint and
+// returns its entities
+func GetPage(endPoint string, bearer string, number int, size int) ([]json.RawMessage, error) {
+	pageURL, err := PageURL(endPoint, number, size)
+	if err != nil {
+		return nil, err
+	}
+
+	var entities []json.RawMessage
+	_, err = Stream(pageURL, bearer, func(entity json.RawMessage) error {
+		entities = append(entities, entity)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entities, nil
+}
+
+// PageURL returns the URL of a page of a list endpoint
+func PageURL(endPoint string, number int, size int) (string, error) {
+	u, err := url.Parse(endPoint)
+	if err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+	query.Set(PageParam, strconv.Itoa(number))
+	query.Set(SizeParam, strconv.Itoa(size))
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
+}
+
+// ListEntity is a helper function to read the pages of a list endpoint
+// of the 1Source REST API, up to the limit of opts, and return the
+// entities as one JSON array
+func ListEntity(endPoint string, bearer string, header string, opts PageOptions) (string, error) {
+	var entities []string
+
+	pager := NewPager(endPoint, bearer, opts)
+	for pager.Next() {
+		for _, entity := range pager.Page() {
+			entities = append(entities, string(entity))
+		}
+	}
+
+	if err := pager.Err(); err != nil {
+		slog.Error("Error GET entity pages", "entity", header, "error", err)
+
+		return "", err
+	}
+
+	return "[" + strings.Join(entities, ",") + "]", nil
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dharm-kapadia/1source-go/models"
+	"github.com/dharm-kapadia/1source-go/simulator"
+)
+
+// contractIds returns the ids of a JSON array of contracts
+func contractIds(t *testing.T, data string) []string {
+	t.Helper()
+
+	var contracts models.Contracts
+	if err := json.Unmarshal([]byte(data), &contracts); err != nil {
+		t.Fatalf("list cannot be decoded: %v", err)
+	}
+
+	ids := make([]string, len(contracts))
+	for i, c := range contracts {
+		ids[i] = c.ContractId
+	}
+
+	return ids
+}
+
+func TestListEntityPages(t *testing.T) {
+	sim, server := simulator.NewServer()
+	defer server.Close()
+
+	proposal := []byte(`{"trade":{"transactingParties":[` +
+		`{"partyRole":"LENDER","party":{"partyId":"TLEN-US"}},` +
+		`{"partyRole":"BORROWER","party":{"partyId":"TBORR-US"}}]}}`)
+
+	var proposed []string
+	for i := 0; i < 7; i++ {
+		id, err := sim.Propose("TLEN-US", proposal)
+		if err != nil {
+			t.Fatal(err)
+		}
+		proposed = append(proposed, id)
+	}
+
+	access, _, err := sim.Login("TestLender1User", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bearer := "Bearer " + access
+	endPoint := server.URL + simulator.LedgerPath + "contracts"
+
+	for _, tc := range []struct {
+		name     string
+		opts     PageOptions
+		expected int
+	}{
+		{"all pages", PageOptions{Size: 3}, 7},
+		{"exact pages", PageOptions{Size: 7}, 7},
+		{"limit", PageOptions{Size: 3, Limit: 5}, 5},
+		{"limit under page size", PageOptions{Size: 100, Limit: 2}, 2},
+		{"prefetch", PageOptions{Size: 2, Prefetch: 3}, 7},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := ListEntity(endPoint, bearer, "1Source Contracts", tc.opts)
+			if err != nil {
+				t.Fatalf("ListEntity() error = %v", err)
+			}
+
+			ids := contractIds(t, data)
+			if len(ids) != tc.expected {
+				t.Fatalf("ListEntity() returned %d contracts, expected %d", len(ids), tc.expected)
+			}
+			for i, id := range ids {
+				if id != proposed[i] {
+					t.Errorf("contract %d = %s, expected %s", i, id, proposed[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPagerUnpagedEndpoint(t *testing.T) {
+	var calls atomic.Int32
+
+	// An endpoint ignoring the paging parameters
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		fmt.Fprint(w, `[{"eventId":1},{"eventId":2},{"eventId":3}]`)
+	}))
+	defer server.Close()
+
+	pager := NewPager(server.URL, "Bearer x", PageOptions{Size: 2})
+
+	count := 0
+	for pager.Next() {
+		count += len(pager.Page())
+	}
+
+	if err := pager.Err(); err != nil {
+		t.Fatalf("Err() = %v", err)
+	}
+	if count != 3 || calls.Load() != 1 {
+		t.Errorf("read %d entities in %d calls, expected 3 in 1", count, calls.Load())
+	}
+}
+
+func TestPagerUnpagedEndpointFullPage(t *testing.T) {
+	var calls atomic.Int32
+
+	// An endpoint ignoring the paging parameters, whose list is exactly
+	// one page long
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		fmt.Fprint(w, `[{"eventId":1},{"eventId":2}]`)
+	}))
+	defer server.Close()
+
+	for _, prefetch := range []int{0, 3} {
+		calls.Store(0)
+		pager := NewPager(server.URL, "Bearer x", PageOptions{Size: 2, Prefetch: prefetch})
+
+		count := 0
+		for pager.Next() && calls.Load() < 50 {
+			count += len(pager.Page())
+		}
+
+		if err := pager.Err(); err != nil {
+			t.Fatalf("Err() = %v", err)
+		}
+		if count != 2 {
+			t.Errorf("prefetch %d: read %d entities, expected 2", prefetch, count)
+		}
+	}
+}
@@ -6,208 +6,158 @@ import (
 	"context"
 	"encoding/json"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
-	"net/url"
 	"time"
 
+	"github.com/dharm-kapadia/1source-go/logging"
 	"github.com/dharm-kapadia/1source-go/models"
+	"github.com/dharm-kapadia/1source-go/redact"
 )
 
-// PostProposeContract will perform an HTTP POST operation
-// against the 1Source REST API to propose a contract
-// https://www.kirandev.com/http-post-golang
-func PostProposeContract(apiEndPoint string, bearer string, body []byte) (string, error) {
+// Post performs an HTTP POST operation on the 1Source REST API.
+// It is shared by the contract propose, cancel and decline calls.
+// It returns the response body when the API answers with the
+// expected HTTP status, and an error otherwise.
+// When DryRun is set, the request is written to DryRunOutput instead
+// of being sent and ErrDryRun is returned.
+func Post(apiEndPoint string, bearer string, body []byte, expectedStatus int, action string) ([]byte, error) {
 	ctx := context.Background()
-	transport := &http.Transport{
-		Proxy: func(r *http.Request) (*url.URL, error) {
-			r.Header.Set("Authorization", bearer)
-			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-			return nil, nil
-		},
-	}
+	requestId := logging.NewRequestId()
+	logger := slog.With("request_id", requestId)
 
-	client := &http.Client{
-		Transport: transport,
-		Timeout:   time.Duration(15) * time.Second,
-	}
+	client := newClient(bearer, time.Duration(15)*time.Second)
 
 	request, err := http.NewRequestWithContext(ctx, "POST", apiEndPoint, bytes.NewBuffer(body))
+
+	if err != nil {
+		logger.Error("Error creating new HTTP Request", "error", err)
+		return nil, redact.Err(err)
+	}
+
 	request.Header.Set("Authorization", bearer)
 	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("X-Request-ID", requestId)
 
-	if err != nil {
-		log.Println("Error creating new HTTP Request: ", err)
-		return "", err
+	if DryRun {
+		logger.Info("Dry run, not calling API endpoint", "method", "POST", "url", apiEndPoint)
+		if err := WriteDryRun(DryRunOutput, request, body); err != nil {
+			return nil, err
+		}
+
+		return nil, ErrDryRun
 	}
 
-	log.Println("Calling API endpoint: ", apiEndPoint)
+	logger.Info("Calling API endpoint", "method", "POST", "url", apiEndPoint, "action", action)
+	logger.Debug("API request body", "body", redact.String(string(body)))
+	start := time.Now()
 	resp, err := client.Do(request)
 
+	if err != nil {
+		logger.Error("Error in HTTP POST API call", "error", err)
+		audit(Call{Action: action, URL: apiEndPoint, Body: body, Err: err})
+		return nil, redact.Err(err)
+	}
+
 	// Close response body
 	defer func() {
 		err := resp.Body.Close()
 		if err != nil {
-			log.Fatal(err)
+			logger.Warn("Error closing Body", "error", err)
 		}
 	}()
 
-	if err != nil {
-		log.Fatal("Error in HTTP POST API call: ", err)
-		return "", err
-	}
-
 	respBody, err := io.ReadAll(resp.Body)
 
 	if err != nil {
-		log.Fatal("Error reading HTTP POST response: ", err)
-		return "", err
+		logger.Error("Error reading HTTP POST response", "error", err)
+		audit(Call{Action: action, URL: apiEndPoint, Body: body, Status: resp.StatusCode, Err: err})
+		return nil, redact.Err(err)
 	}
 
-	if resp.StatusCode != http.StatusCreated {
-		log.Println("Error proposing contract. HTTP Response Status:", resp.Status)
-	} else {
-		var cir models.ContractInitiationResponse
+	logger.Info("API response", "status", resp.StatusCode, "elapsed", time.Since(start))
+	logger.Debug("API response body", "body", redact.String(string(respBody)))
 
-		err := json.Unmarshal(respBody, &cir)
-		if err != nil {
-			return "", err
-		}
-
-		return cir.Message, err
+	if resp.StatusCode != expectedStatus {
+		logger.Error("Unexpected HTTP response status", "action", action, "status", resp.Status)
+		err := &StatusError{Action: action, StatusCode: resp.StatusCode, Status: resp.Status}
+		audit(Call{Action: action, URL: apiEndPoint, Body: body, Status: resp.StatusCode, Response: respBody, Err: err})
+		return nil, err
 	}
 
-	return "", err
+	audit(Call{Action: action, URL: apiEndPoint, Body: body, Status: resp.StatusCode, Response: respBody})
+
+	return respBody, err
 }
 
-// PostCancelContract will perform an HTTP POST operation
-// against the 1Source REST API to cancel a contract
-// https://www.kirandev.com/http-post-golang
-func PostCancelContract(apiEndPoint string, bearer string) (string, error) {
-	ctx := context.Background()
-	transport := &http.Transport{
-		Proxy: func(r *http.Request) (*url.URL, error) {
-			r.Header.Set("Authorization", bearer)
-			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-			return nil, nil
-		},
-	}
+// ProposeContract will perform an HTTP POST operation against the
+// 1Source REST API to propose a contract and returns the decoded response
+func ProposeContract(apiEndPoint string, bearer string, body []byte) (*models.ContractInitiationResponse, error) {
+	respBody, err := Post(apiEndPoint, bearer, body, http.StatusCreated, ActionPropose)
 
-	client := &http.Client{
-		Transport: transport,
-		Timeout:   time.Duration(15) * time.Second,
+	if err != nil {
+		return nil, err
 	}
 
-	request, err := http.NewRequestWithContext(ctx, "POST", apiEndPoint, nil)
-	request.Header.Set("Authorization", bearer)
-	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var cir models.ContractInitiationResponse
 
+	err = json.Unmarshal(respBody, &cir)
 	if err != nil {
-		log.Println("Error creating new HTTP Request: ", err)
-		return "", err
+		return nil, err
 	}
 
-	log.Println("Calling API endpoint: ", apiEndPoint)
-	resp, err := client.Do(request)
+	return &cir, err
+}
 
-	// Close response body
-	defer func() {
-		err := resp.Body.Close()
-		if err != nil {
-			log.Fatal(err)
-		}
-	}()
+// PostProposeContract will perform an HTTP POST operation
+// against the 1Source REST API to propose a contract
+// https://www.kirandev.com/http-post-golang
+func PostProposeContract(apiEndPoint string, bearer string, body []byte) (string, error) {
+	cir, err := ProposeContract(apiEndPoint, bearer, body)
 
 	if err != nil {
-		log.Fatal("Error in HTTP POST API call: ", err)
 		return "", err
 	}
 
-	respBody, err := io.ReadAll(resp.Body)
+	return cir.Message, err
+}
+
+// PostCancelContract will perform an HTTP POST operation
+// against the 1Source REST API to cancel a contract
+// https://www.kirandev.com/http-post-golang
+func PostCancelContract(apiEndPoint string, bearer string) (string, error) {
+	respBody, err := Post(apiEndPoint, bearer, nil, http.StatusOK, ActionCancel)
 
 	if err != nil {
-		log.Fatal("Error reading HTTP POST response: ", err)
 		return "", err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		log.Println("Error canceling proposed contract. HTTP Response Status:", resp.Status)
-	} else {
-		var ccr models.ContractCancelReponse
+	var ccr models.ContractCancelReponse
 
-		err := json.Unmarshal(respBody, &ccr)
-		if err != nil {
-			return "", err
-		}
-
-		return ccr.Message, err
+	err = json.Unmarshal(respBody, &ccr)
+	if err != nil {
+		return "", err
 	}
 
-	return "", err
+	return ccr.Message, err
 }
 
 // PostDeclineContract will perform an HTTP POST operation
 // against the 1Source REST API to decline a contract
 // https://www.kirandev.com/http-post-golang
 func PostDeclineContract(apiEndPoint string, bearer string) (string, error) {
-	ctx := context.Background()
-	transport := &http.Transport{
-		Proxy: func(r *http.Request) (*url.URL, error) {
-			r.Header.Set("Authorization", bearer)
-			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-			return nil, nil
-		},
-	}
-
-	client := &http.Client{
-		Transport: transport,
-		Timeout:   time.Duration(15) * time.Second,
-	}
-
-	request, err := http.NewRequestWithContext(ctx, "POST", apiEndPoint, nil)
-	request.Header.Set("Authorization", bearer)
-	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	respBody, err := Post(apiEndPoint, bearer, nil, http.StatusOK, ActionDecline)
 
 	if err != nil {
-		log.Println("Error creating new HTTP Request: ", err)
 		return "", err
 	}
 
-	log.Println("Calling API endpoint: ", apiEndPoint)
-	resp, err := client.Do(request)
-
-	// Close response body
-	defer func() {
-		err := resp.Body.Close()
-		if err != nil {
-			log.Fatal(err)
-		}
-	}()
+	var cdr models.ContractDeclineReponse
 
+	err = json.Unmarshal(respBody, &cdr)
 	if err != nil {
-		log.Fatal("Error in HTTP POST API call: ", err)
 		return "", err
 	}
 
-	respBody, err := io.ReadAll(resp.Body)
-
-	if err != nil {
-		log.Fatal("Error reading HTTP POST response: ", err)
-		return "", err
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		log.Println("Error declining proposed contract. HTTP Response Status:", resp.Status)
-	} else {
-		var cdr models.ContractDeclineReponse
-
-		err := json.Unmarshal(respBody, &cdr)
-		if err != nil {
-			return "", err
-		}
-
-		return cdr.Message, err
-	}
-
-	return "", err
+	return cdr.Message, err
 }
@@ -0,0 +1,96 @@
+// Package api provides functions for HTTP verb access to 1Source REST API.
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/dharm-kapadia/1source-go/redact"
+)
+
+// Stream performs an HTTP GET of a list endpoint of the 1Source REST API
+// and decodes the JSON array of the response element by element, calling
+// fn with each element as it is read. Only one element is held in memory
+// at a time. Stream stops at the first error returned by fn, and returns
+// the number of elements passed to fn.
+func Stream(apiEndPoint string, bearer string, fn func(json.RawMessage) error) (int, error) {
+	response, logger, err := get(apiEndPoint, bearer)
+	if err != nil {
+		return 0, err
+	}
+	defer closeBody(response, logger)
+
+	decoder := json.NewDecoder(response.Body)
+
+	token, err := decoder.Token()
+	if err != nil {
+		return 0, redact.Err(fmt.Errorf("decoding %s: %w", apiEndPoint, err))
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return 0, fmt.Errorf("decoding %s: expected a JSON array", redact.String(apiEndPoint))
+	}
+
+	count := 0
+	for decoder.More() {
+		var element json.RawMessage
+		if err := decoder.Decode(&element); err != nil {
+			return count, redact.Err(fmt.Errorf("decoding element %d of %s: %w", count+1, apiEndPoint, err))
+		}
+
+		if err := fn(element); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	// The closing bracket
+	if _, err := decoder.Token(); err != nil {
+		return count, redact.Err(fmt.Errorf("decoding %s: %w", apiEndPoint, err))
+	}
+
+	logger.Info("API response streamed", "elements", count)
+	return count, nil
+}
+
+// StreamPages reads the pages of a list endpoint, up to the limit of
+// opts, calling fn with each element in order. At most one page, and the
+// prefetched ones, are held in memory at a time. It stops at the first
+// error returned by fn, and returns the number of elements passed to fn.
+func StreamPages(endPoint string, bearer string, opts PageOptions, fn func(json.RawMessage) error) (int, error) {
+	count := 0
+
+	pager := NewPager(endPoint, bearer, opts)
+	for pager.Next() {
+		for _, element := range pager.Page() {
+			if err := fn(element); err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+
+	return count, pager.Err()
+}
+
+// StreamChannel streams the elements of a list endpoint into a channel,
+// as Stream does, from a new goroutine. The channel is closed at the end
+// of the list, and the error channel then receives the error which
+// stopped the stream, or nil. The element channel must be drained.
+func StreamChannel(apiEndPoint string, bearer string, buffer int) (<-chan json.RawMessage, <-chan error) {
+	elements := make(chan json.RawMessage, buffer)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+
+		_, err := Stream(apiEndPoint, bearer, func(element json.RawMessage) error {
+			elements <- element
+			return nil
+		})
+
+		close(elements)
+		errs <- err
+	}()
+
+	return elements, errs
+}
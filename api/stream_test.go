@@ -0,0 +1,153 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// eventsServer serves n events as a pretty printed JSON array, written
+// one event at a time
+func eventsServer(n int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "[\n")
+		for i := 1; i <= n; i++ {
+			if i > 1 {
+				fmt.Fprint(w, ",\n")
+			}
+			fmt.Fprintf(w, "  {\n    \"eventId\": %d,\n    \"eventType\": \"TRADE\",\n    \"tags\": [1, [2]]\n  }", i)
+		}
+		fmt.Fprint(w, "\n]\n")
+	}))
+}
+
+func TestStream(t *testing.T) {
+	server := eventsServer(10000)
+	defer server.Close()
+
+	next := uint64(1)
+	count, err := Stream(server.URL, "Bearer x", func(element json.RawMessage) error {
+		var event struct{ EventId uint64 }
+		if err := json.Unmarshal(element, &event); err != nil {
+			return err
+		}
+		if event.EventId != next {
+			return fmt.Errorf("event %d read, expected %d", event.EventId, next)
+		}
+		next++
+		return nil
+	})
+
+	if err != nil || count != 10000 {
+		t.Errorf("Stream() = %d, %v, expected 10000 events", count, err)
+	}
+}
+
+func TestStreamStops(t *testing.T) {
+	server := eventsServer(100)
+	defer server.Close()
+
+	stop := errors.New("stop")
+	count, err := Stream(server.URL, "Bearer x", func(element json.RawMessage) error {
+		return stop
+	})
+
+	if !errors.Is(err, stop) || count != 0 {
+		t.Errorf("Stream() = %d, %v, expected 0, stop", count, err)
+	}
+}
+
+func TestStreamNotArray(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"eventId":1}`)
+	}))
+	defer server.Close()
+
+	if _, err := Stream(server.URL, "Bearer x", func(json.RawMessage) error { return nil }); err == nil {
+		t.Error("Stream() of an object succeeded")
+	}
+}
+
+func TestStreamChannel(t *testing.T) {
+	server := eventsServer(500)
+	defer server.Close()
+
+	elements, errs := StreamChannel(server.URL, "Bearer x", 16)
+
+	count := 0
+	for range elements {
+		count++
+	}
+
+	if err := <-errs; err != nil || count != 500 {
+		t.Errorf("StreamChannel() sent %d elements, error %v, expected 500", count, err)
+	}
+}
+
+// slowServer serves n events, waiting pause before each one
+func slowServer(n int, pause time.Duration) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher := w.(http.Flusher)
+
+		fmt.Fprint(w, "[")
+		for i := 1; i <= n; i++ {
+			flusher.Flush()
+			time.Sleep(pause)
+
+			if i > 1 {
+				fmt.Fprint(w, ",")
+			}
+			fmt.Fprintf(w, `{"eventId":%d}`, i)
+		}
+		fmt.Fprint(w, "]")
+	}))
+}
+
+func TestStreamLongerThanTimeouts(t *testing.T) {
+	defer func(response, idle time.Duration) { ResponseTimeout, IdleTimeout = response, idle }(ResponseTimeout, IdleTimeout)
+	ResponseTimeout, IdleTimeout = 100*time.Millisecond, 100*time.Millisecond
+
+	// Streamed for about 500ms, with data every 20ms
+	server := slowServer(25, 20*time.Millisecond)
+	defer server.Close()
+
+	count, err := Stream(server.URL, "Bearer x", func(json.RawMessage) error { return nil })
+	if err != nil || count != 25 {
+		t.Errorf("Stream() = %d, %v, expected 25 events", count, err)
+	}
+}
+
+func TestStreamStalled(t *testing.T) {
+	defer func(response, idle time.Duration) { ResponseTimeout, IdleTimeout = response, idle }(ResponseTimeout, IdleTimeout)
+	ResponseTimeout, IdleTimeout = time.Second, 100*time.Millisecond
+
+	server := slowServer(3, 300*time.Millisecond)
+	defer server.Close()
+
+	_, err := Stream(server.URL, "Bearer x", func(json.RawMessage) error { return nil })
+
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("Stream() of a stalled response error = %v, expected a timeout", err)
+	}
+}
+
+func TestGetNoResponse(t *testing.T) {
+	defer func(response time.Duration) { ResponseTimeout = response }(ResponseTimeout)
+	ResponseTimeout = 100 * time.Millisecond
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	var netErr net.Error
+	if _, err := Get(server.URL, "Bearer x"); !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("Get() without a response error = %v, expected a timeout", err)
+	}
+}
@@ -0,0 +1,56 @@
+// Package api provides functions for HTTP verb access to 1Source REST API.
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Nerzal/gocloak/v13"
+)
+
+// Transport carries every call to the 1Source REST API and to KeyCloak.
+// Record and Replay wrap or replace it.
+var Transport http.RoundTripper = http.DefaultTransport
+
+// Timeouts of the GET calls to the 1Source REST API: the response must
+// start within ResponseTimeout, and each read of its body must receive
+// data within IdleTimeout. A response streamed for longer is not cut off
+// as long as data keeps arriving.
+var (
+	ResponseTimeout = 10 * time.Second
+	IdleTimeout     = 30 * time.Second
+)
+
+// newClient returns an HTTP client over Transport which sends bearer,
+// also after a redirect to the same host. A zero timeout sets no overall
+// timeout.
+func newClient(bearer string, timeout time.Duration) *http.Client {
+	return &http.Client{
+		Transport: Transport,
+		Timeout:   timeout,
+		CheckRedirect: func(r *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+
+			// The token only follows a redirect to the host it was sent
+			// to, and never from https to http
+			first := via[0].URL
+			if r.URL.Host == first.Host && (first.Scheme != "https" || r.URL.Scheme == "https") {
+				r.Header.Set("Authorization", bearer)
+			} else {
+				r.Header.Del("Authorization")
+			}
+			return nil
+		},
+	}
+}
+
+// newKeycloakClient returns a KeyCloak client over Transport
+func newKeycloakClient(authURL string) *gocloak.GoCloak {
+	client := gocloak.NewClient(authURL)
+	client.RestyClient().SetTransport(Transport)
+
+	return client
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedirectAuthorization(t *testing.T) {
+	var received []string
+	record := func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Header.Get("Authorization"))
+		w.Write([]byte("[]"))
+	}
+
+	other := httptest.NewServer(http.HandlerFunc(record))
+	defer other.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/same", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/target", http.StatusFound)
+	})
+	mux.HandleFunc("/other", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, other.URL+"/target", http.StatusFound)
+	})
+	mux.HandleFunc("/target", record)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// The token follows a redirect on the same host only
+	for _, path := range []string{"/same", "/other"} {
+		if _, err := Get(server.URL+path, "Bearer token"); err != nil {
+			t.Fatalf("Get(%s) error = %v", path, err)
+		}
+	}
+
+	if len(received) != 2 || received[0] != "Bearer token" || received[1] != "" {
+		t.Errorf("Authorization after redirects = %q, expected the token on the same host only", received)
+	}
+}
@@ -0,0 +1,194 @@
+// Package audit keeps an append-only journal of the calls which change
+// state in the 1Source REST API, such as proposals, cancels and declines.
+//
+// The journal is an NDJSON file. Every record holds the hash of the
+// record before it and its own hash, so that changing, inserting or
+// removing a record breaks the chain and is detected by Verify.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Path is the journal file
+var Path = "1source-audit.ndjson"
+
+// GenesisHash is the previous hash of the first record of a journal
+var GenesisHash = strings.Repeat("0", 64)
+
+// Record is one mutating call in the journal
+type Record struct {
+	Seq       uint64 `json:"seq"`
+	Timestamp string `json:"timestamp"`
+	Operator  string `json:"operator"`
+	Username  string `json:"username"`
+	Profile   string `json:"profile"`
+	Operation string `json:"operation"`
+	TargetId  string `json:"targetId"`
+	BodyHash  string `json:"bodyHash"`
+	Status    int    `json:"status"`
+	Error     string `json:"error,omitempty"`
+	PrevHash  string `json:"prevHash"`
+	Hash      string `json:"hash,omitempty"`
+}
+
+// ComputeHash returns the hash of the record, computed over every field
+// but Hash itself
+func (r Record) ComputeHash() string {
+	r.Hash = ""
+
+	// A record holds only strings and numbers, it always encodes
+	b, _ := json.Marshal(r)
+
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// HashBody returns the hash of a request body as stored in a record
+func HashBody(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// mu serializes appends from concurrent calls
+var mu sync.Mutex
+
+// Append adds a record to the journal, setting its sequence number,
+// timestamp and hashes. The record is chained to the last record of the
+// file, read under an exclusive file lock, so concurrent processes never
+// chain two records to the same one.
+func Append(r Record) (Record, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	f, err := os.OpenFile(Path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return r, err
+	}
+	defer f.Close()
+
+	if err := lock(f); err != nil {
+		return r, fmt.Errorf("locking audit journal '%s': %w", Path, err)
+	}
+	defer unlock(f)
+
+	last, err := lastRecord(f)
+	if err != nil {
+		return r, err
+	}
+
+	r.Seq, r.PrevHash = 1, GenesisHash
+	if last != nil {
+		r.Seq, r.PrevHash = last.Seq+1, last.Hash
+	}
+	if r.Timestamp == "" {
+		r.Timestamp = time.Now().UTC().Format(time.RFC3339Nano)
+	}
+	r.Hash = r.ComputeHash()
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		return r, err
+	}
+
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		return r, err
+	}
+
+	return r, f.Sync()
+}
+
+// tailSize is how much of the end of the journal is read to find the
+// last record, far more than the size of one record
+const tailSize = 64 * 1024
+
+// lastRecord returns the last record of the journal, nil when it is empty
+func lastRecord(f *os.File) (*Record, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	offset := info.Size() - tailSize
+	if offset < 0 {
+		offset = 0
+	}
+
+	tail := make([]byte, info.Size()-offset)
+	if _, err := f.ReadAt(tail, offset); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	tail = bytes.TrimRight(tail, "\n")
+	if len(tail) == 0 {
+		return nil, nil
+	}
+
+	line := tail[bytes.LastIndexByte(tail, '\n')+1:]
+
+	var r Record
+	if err := json.Unmarshal(line, &r); err != nil {
+		return nil, fmt.Errorf("last record of audit journal '%s' cannot be read: %w", Path, err)
+	}
+
+	return &r, nil
+}
+
+// Read returns the records of the journal
+func Read(r io.Reader) ([]Record, error) {
+	var records []Record
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), tailSize)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return records, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+
+	return records, scanner.Err()
+}
+
+// ErrBroken is wrapped by the errors returned by Verify
+var ErrBroken = errors.New("audit journal chain is broken")
+
+// Verify checks the sequence numbers and the hash chain of the records.
+// The error names the first record which does not match.
+func Verify(records []Record) error {
+	prevHash := GenesisHash
+
+	for i, r := range records {
+		switch {
+		case r.Seq != uint64(i+1):
+			return fmt.Errorf("%w: record %d has sequence number %d, records were inserted or removed", ErrBroken, i+1, r.Seq)
+		case r.PrevHash != prevHash:
+			return fmt.Errorf("%w: record %d does not follow record %d", ErrBroken, r.Seq, r.Seq-1)
+		case r.Hash != r.ComputeHash():
+			return fmt.Errorf("%w: record %d was changed", ErrBroken, r.Seq)
+		}
+
+		prevHash = r.Hash
+	}
+
+	return nil
+}
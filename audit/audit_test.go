@@ -0,0 +1,66 @@
+package audit
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// helperEnv names the journal appended to by TestAppendHelper when run as
+// a separate process
+const helperEnv = "AUDIT_APPEND_HELPER"
+
+func TestAppendHelper(t *testing.T) {
+	if os.Getenv(helperEnv) == "" {
+		t.Skip("run by TestAppendConcurrentProcesses")
+	}
+
+	Path = os.Getenv(helperEnv)
+	for i := 0; i < 25; i++ {
+		if _, err := Append(Record{Operation: "propose", Status: 201}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestAppendConcurrentProcesses(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("appends are only locked within a process on Windows")
+	}
+
+	path := filepath.Join(t.TempDir(), "audit.ndjson")
+
+	var cmds []*exec.Cmd
+	for i := 0; i < 4; i++ {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestAppendHelper$")
+		cmd.Env = append(os.Environ(), helperEnv+"="+path)
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		cmds = append(cmds, cmd)
+	}
+	for _, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Fatalf("append process failed: %v", err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	records, err := Read(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 100 {
+		t.Errorf("journal holds %d records, expected 100", len(records))
+	}
+	if err := Verify(records); err != nil {
+		t.Errorf("Verify() = %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+// Package audit keeps an append-only journal of the calls which change
+// state in the 1Source REST API, such as proposals, cancels and declines.
+package audit
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// WriteCSV writes the records as CSV, with a header row
+func WriteCSV(w io.Writer, records []Record) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{"seq", "timestamp", "operator", "username", "profile", "operation", "targetId", "bodyHash", "status", "error", "prevHash", "hash"}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, r := range records {
+		row := []string{
+			strconv.FormatUint(r.Seq, 10),
+			r.Timestamp,
+			r.Operator,
+			r.Username,
+			r.Profile,
+			r.Operation,
+			r.TargetId,
+			r.BodyHash,
+			strconv.Itoa(r.Status),
+			r.Error,
+			r.PrevHash,
+			r.Hash,
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,18 @@
+//go:build !unix
+
+// Package audit keeps an append-only journal of the calls which change
+// state in the 1Source REST API, such as proposals, cancels and declines.
+package audit
+
+import "os"
+
+// lock does nothing where advisory file locks are not available:
+// appends are only serialized within the process
+func lock(f *os.File) error {
+	return nil
+}
+
+// unlock releases the lock taken by lock
+func unlock(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,27 @@
+//go:build unix
+
+// Package audit keeps an append-only journal of the calls which change
+// state in the 1Source REST API, such as proposals, cancels and declines.
+package audit
+
+import (
+	"os"
+	"syscall"
+)
+
+// lock takes an exclusive advisory lock on the journal, held until
+// unlock or until the file is closed, so that concurrent processes
+// append one at a time
+func lock(f *os.File) error {
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+		if err != syscall.EINTR {
+			return err
+		}
+	}
+}
+
+// unlock releases the lock taken by lock
+func unlock(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
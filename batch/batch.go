@@ -0,0 +1,105 @@
+// Package batch runs many 1Source REST API calls concurrently over one
+// authenticated session and keeps track of their results.
+package batch
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/dharm-kapadia/1source-go/api"
+)
+
+// Result records the outcome of one call made as part of a batch
+type Result struct {
+	Input      string `json:"input"`
+	ContractId string `json:"contractId,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Time       string `json:"time"`
+
+	// DryRun is set when the request was printed rather than sent
+	DryRun bool `json:"-"`
+}
+
+// Succeeded reports whether the call was sent and completed without error
+func (r Result) Succeeded() bool {
+	return r.Error == "" && !r.DryRun
+}
+
+// Failed reports whether the call was sent and failed
+func (r Result) Failed() bool {
+	return r.Error != ""
+}
+
+// NewResult builds the Result for input from the contract id and error
+// returned by a call
+func NewResult(input string, contractId string, err error) Result {
+	result := Result{
+		Input:      input,
+		ContractId: contractId,
+		Time:       time.Now().UTC().Format(time.RFC3339),
+	}
+
+	switch {
+	case errors.Is(err, api.ErrDryRun):
+		result.DryRun = true
+	case err != nil:
+		result.Error = err.Error()
+	}
+
+	return result
+}
+
+// Run calls fn for every item using at most concurrency goroutines.
+// done is called once per item as results complete; calls to done are
+// serialized so it may write to shared state without locking.
+func Run[T any](items []T, concurrency int, fn func(T) Result, done func(Result)) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	queue := make(chan T)
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range queue {
+				result := fn(item)
+
+				mu.Lock()
+				done(result)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, item := range items {
+		queue <- item
+	}
+	close(queue)
+
+	wg.Wait()
+}
+
+// Summary counts the successful, failed and dry run results of a batch
+type Summary struct {
+	Succeeded int
+	Failed    int
+	Skipped   int
+	DryRun    int
+}
+
+// Add counts result in the summary
+func (s *Summary) Add(result Result) {
+	switch {
+	case result.DryRun:
+		s.DryRun++
+	case result.Succeeded():
+		s.Succeeded++
+	default:
+		s.Failed++
+	}
+}
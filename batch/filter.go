@@ -0,0 +1,67 @@
+// Package batch runs many 1Source REST API calls concurrently over one
+// authenticated session and keeps track of their results.
+package batch
+
+import (
+	"strings"
+
+	"github.com/dharm-kapadia/1source-go/models"
+)
+
+// Filter selects contracts for a bulk operation. Empty fields match
+// every contract; set fields must all match.
+type Filter struct {
+	Party      string
+	Ticker     string
+	TradeDate  string
+	VenueRefId string
+}
+
+// IsEmpty reports whether no filter field is set
+func (f Filter) IsEmpty() bool {
+	return f == Filter{}
+}
+
+// Match reports whether the contract matches every set filter field.
+// Party matches the partyId of any of the transacting parties.
+func (f Filter) Match(contract models.Contract) bool {
+	trade := contract.Trade
+
+	if f.Ticker != "" && !strings.EqualFold(trade.Instrument.Ticker, f.Ticker) {
+		return false
+	}
+
+	if f.TradeDate != "" && trade.TradeDate != f.TradeDate {
+		return false
+	}
+
+	if f.VenueRefId != "" && trade.ExecutionVenue.Platform.VenueRefId != f.VenueRefId {
+		return false
+	}
+
+	if f.Party != "" {
+		for _, tp := range trade.TransactingParties {
+			if strings.EqualFold(tp.Party.PartyId, f.Party) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return true
+}
+
+// SelectProposed returns the contracts in the PROPOSED state which match
+// the filter
+func SelectProposed(contracts models.Contracts, f Filter) models.Contracts {
+	var selected models.Contracts
+
+	for _, contract := range contracts {
+		if contract.ContractStatus == models.ContractStatusProposed && f.Match(contract) {
+			selected = append(selected, contract)
+		}
+	}
+
+	return selected
+}
@@ -0,0 +1,101 @@
+package batch
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/dharm-kapadia/1source-go/models"
+)
+
+const filterContracts = `[
+	{"contractId":"c1","contractStatus":"PROPOSED","trade":{
+		"executionVenue":{"platform":{"venueRefId":"VENUE-1"}},
+		"instrument":{"ticker":"IBM"},"tradeDate":"2024-01-29",
+		"transactingParties":[
+			{"partyRole":"LENDER","party":{"partyId":"TLEN-US"}},
+			{"partyRole":"BORROWER","party":{"partyId":"TBORR-US"}}]}},
+	{"contractId":"c2","contractStatus":"OPEN","trade":{
+		"executionVenue":{"platform":{"venueRefId":"VENUE-1"}},
+		"instrument":{"ticker":"IBM"},"tradeDate":"2024-01-29",
+		"transactingParties":[
+			{"partyRole":"LENDER","party":{"partyId":"TLEN-US"}},
+			{"partyRole":"BORROWER","party":{"partyId":"TBORR-US"}}]}},
+	{"contractId":"c3","contractStatus":"PROPOSED","trade":{
+		"executionVenue":{"platform":{"venueRefId":"VENUE-2"}},
+		"instrument":{"ticker":"MSFT"},"tradeDate":"2024-01-30",
+		"transactingParties":[
+			{"partyRole":"LENDER","party":{"partyId":"OTHER-LEN"}},
+			{"partyRole":"BORROWER","party":{"partyId":"TBORR-US"}}]}}
+]`
+
+// contracts decodes the test contracts
+func contracts(t *testing.T) models.Contracts {
+	t.Helper()
+
+	var contracts models.Contracts
+	if err := json.Unmarshal([]byte(filterContracts), &contracts); err != nil {
+		t.Fatal(err)
+	}
+	return contracts
+}
+
+func TestFilterMatch(t *testing.T) {
+	contract := contracts(t)[0]
+
+	tests := []struct {
+		filter   Filter
+		expected bool
+	}{
+		{Filter{}, true},
+
+		// Party matches either side, ignoring case
+		{Filter{Party: "TLEN-US"}, true},
+		{Filter{Party: "tborr-us"}, true},
+		{Filter{Party: "OTHER-LEN"}, false},
+
+		// Ticker ignores case, trade date and venue are exact
+		{Filter{Ticker: "ibm"}, true},
+		{Filter{Ticker: "MSFT"}, false},
+		{Filter{TradeDate: "2024-01-29"}, true},
+		{Filter{TradeDate: "2024-01-30"}, false},
+		{Filter{VenueRefId: "VENUE-1"}, true},
+		{Filter{VenueRefId: "venue-1"}, false},
+
+		// Every set field must match
+		{Filter{Party: "TBORR-US", Ticker: "IBM", TradeDate: "2024-01-29", VenueRefId: "VENUE-1"}, true},
+		{Filter{Party: "TBORR-US", Ticker: "IBM", TradeDate: "2024-01-29", VenueRefId: "VENUE-2"}, false},
+		{Filter{Party: "OTHER-LEN", Ticker: "IBM"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.filter.Match(contract); got != tt.expected {
+			t.Errorf("Filter%+v.Match(c1) = %v, expected %v", tt.filter, got, tt.expected)
+		}
+	}
+}
+
+func TestSelectProposed(t *testing.T) {
+	tests := []struct {
+		filter   Filter
+		expected []string
+	}{
+		// The OPEN contract c2 is never selected, even when it matches
+		{Filter{}, []string{"c1", "c3"}},
+		{Filter{Party: "TBORR-US"}, []string{"c1", "c3"}},
+		{Filter{Ticker: "IBM"}, []string{"c1"}},
+		{Filter{VenueRefId: "VENUE-2"}, []string{"c3"}},
+		{Filter{TradeDate: "2024-02-01"}, nil},
+	}
+
+	for _, tt := range tests {
+		var got []string
+		for _, contract := range SelectProposed(contracts(t), tt.filter) {
+			got = append(got, contract.ContractId)
+		}
+
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("SelectProposed(%+v) = %q, expected %q", tt.filter, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+// Package batch runs many 1Source REST API calls concurrently over one
+// authenticated session and keeps track of their results.
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Input is one contract proposal read from a directory or NDJSON stream.
+// Name identifies the proposal in the results file: the file path for
+// a directory, or "file:line" for an NDJSON stream.
+type Input struct {
+	Name string
+	Body []byte
+}
+
+// LoadInputs reads contract proposals from path. A directory yields one
+// proposal per *.json file, in file name order. Any other path, or "-"
+// for standard input, is read as NDJSON with one proposal per line.
+func LoadInputs(path string) ([]Input, error) {
+	if path == "-" {
+		return readNDJSON("-", os.Stdin)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		return readNDJSON(path, file)
+	}
+
+	files, err := filepath.Glob(filepath.Join(path, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	inputs := make([]Input, 0, len(files))
+	for _, name := range files {
+		body, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		inputs = append(inputs, Input{Name: name, Body: body})
+	}
+
+	return inputs, nil
+}
+
+// readNDJSON reads one proposal per non-blank line of r
+func readNDJSON(name string, r io.Reader) ([]Input, error) {
+	var inputs []Input
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		body := bytes.TrimSpace(scanner.Bytes())
+		if len(body) == 0 {
+			continue
+		}
+
+		if !json.Valid(body) {
+			return nil, fmt.Errorf("%s:%d: invalid JSON", name, line)
+		}
+
+		inputs = append(inputs, Input{
+			Name: fmt.Sprintf("%s:%d", name, line),
+			Body: append([]byte(nil), body...),
+		})
+	}
+
+	return inputs, scanner.Err()
+}
+
+// ReadResults reads a results file written by a previous run and returns
+// the names of the inputs which were proposed successfully. An input once
+// recorded as succeeded stays so, whatever later results say, as its
+// contract exists. A missing results file yields an empty set. A
+// truncated last record, left by an interrupted run, is an error: the
+// outcome of its input is unknown.
+func ReadResults(path string) (map[string]bool, error) {
+	succeeded := make(map[string]bool)
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return succeeded, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		record := bytes.TrimSpace(scanner.Bytes())
+		if len(record) == 0 {
+			continue
+		}
+
+		var result Result
+		if err := json.Unmarshal(record, &result); err != nil {
+			return nil, fmt.Errorf("results file '%s' line %d is not a complete result, "+
+				"check whether its input was proposed and remove the line: %w", path, line, err)
+		}
+
+		succeeded[result.Input] = succeeded[result.Input] || result.Succeeded()
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading results file '%s': %w", path, err)
+	}
+
+	return succeeded, nil
+}
+
+// ResultWriter appends results to a results file as NDJSON, so the file
+// is usable for a resume even when a run is interrupted
+type ResultWriter struct {
+	file    *os.File
+	encoder *json.Encoder
+}
+
+// NewResultWriter opens the results file at path. Existing results are
+// kept when appending, otherwise the file is truncated.
+func NewResultWriter(path string, appending bool) (*ResultWriter, error) {
+	flags := os.O_CREATE | os.O_WRONLY
+	if appending {
+		flags |= os.O_APPEND
+	} else {
+		flags |= os.O_TRUNC
+	}
+
+	file, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ResultWriter{file: file, encoder: json.NewEncoder(file)}, nil
+}
+
+// Write appends one result to the results file
+func (w *ResultWriter) Write(result Result) error {
+	return w.encoder.Encode(result)
+}
+
+// Close closes the results file
+func (w *ResultWriter) Close() error {
+	return w.file.Close()
+}
+
+// Pending returns the inputs which are not recorded as succeeded
+func Pending(inputs []Input, succeeded map[string]bool) []Input {
+	var pending []Input
+
+	for _, input := range inputs {
+		if !succeeded[input.Name] {
+			pending = append(pending, input)
+		}
+	}
+
+	return pending
+}
+
+// ProposeAll proposes every input with at most concurrency calls in
+// flight. propose returns the id of the proposed contract. report is
+// called with each result as soon as it is known.
+func ProposeAll(inputs []Input, concurrency int, propose func(body []byte) (string, error), report func(Result)) Summary {
+	var summary Summary
+
+	Run(inputs, concurrency, func(input Input) Result {
+		contractId, err := propose(input.Body)
+		return NewResult(input.Name, contractId, err)
+	}, func(result Result) {
+		summary.Add(result)
+		report(result)
+	})
+
+	return summary
+}
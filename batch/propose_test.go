@@ -0,0 +1,163 @@
+package batch
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dharm-kapadia/1source-go/api"
+)
+
+// writeFile writes a test file and returns its path
+func writeFile(t *testing.T, dir string, name string, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// names returns the names of inputs
+func names(inputs []Input) []string {
+	var names []string
+	for _, input := range inputs {
+		names = append(names, input.Name)
+	}
+	return names
+}
+
+func TestLoadInputsDirectory(t *testing.T) {
+	dir := t.TempDir()
+	b := writeFile(t, dir, "b.json", `{"trade":"b"}`)
+	a := writeFile(t, dir, "a.json", `{"trade":"a"}`)
+	writeFile(t, dir, "notes.txt", "not a proposal")
+
+	inputs, err := LoadInputs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only *.json files, in file name order, named by their path
+	if got := names(inputs); !reflect.DeepEqual(got, []string{a, b}) {
+		t.Errorf("LoadInputs() = %q, expected %q", got, []string{a, b})
+	}
+	if string(inputs[0].Body) != `{"trade":"a"}` {
+		t.Errorf("LoadInputs() body of %s = %s", a, inputs[0].Body)
+	}
+}
+
+func TestLoadInputsNDJSON(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "proposals.ndjson", "{\"trade\":1}\n\n  \n{\"trade\":2}\n{\"trade\":3}")
+
+	inputs, err := LoadInputs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Blank lines are skipped but counted, so names match the file lines
+	expected := []string{path + ":1", path + ":4", path + ":5"}
+	if got := names(inputs); !reflect.DeepEqual(got, expected) {
+		t.Errorf("LoadInputs() = %q, expected %q", got, expected)
+	}
+	if string(inputs[2].Body) != `{"trade":3}` {
+		t.Errorf("LoadInputs() body of line 5 = %s", inputs[2].Body)
+	}
+
+	path = writeFile(t, t.TempDir(), "proposals.ndjson", "{\"trade\":1}\n{\"trade\":\n")
+	if _, err := LoadInputs(path); err == nil || !strings.Contains(err.Error(), path+":2: invalid JSON") {
+		t.Errorf("LoadInputs() of invalid JSON error = %v, expected line 2", err)
+	}
+}
+
+func TestResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.ndjson")
+	inputs := []Input{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}}
+
+	// A first run proposes a and c, b fails and the run stops before d
+	writer, err := NewResultWriter(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer.Write(NewResult("a", "contract-a", nil))
+	writer.Write(NewResult("b", "", errors.New("400 Bad Request")))
+	writer.Write(NewResult("c", "contract-c", nil))
+	writer.Close()
+
+	// A later failure of c, e.g. a duplicate refused by the API, does not
+	// make it pending again as its contract exists
+	writer, err = NewResultWriter(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer.Write(NewResult("c", "", errors.New("409 Conflict")))
+	writer.Close()
+
+	succeeded, err := ReadResults(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := names(Pending(inputs, succeeded)); !reflect.DeepEqual(got, []string{"b", "d"}) {
+		t.Errorf("Pending() = %q, expected [b d]", got)
+	}
+}
+
+func TestReadResultsMissing(t *testing.T) {
+	succeeded, err := ReadResults(filepath.Join(t.TempDir(), "results.ndjson"))
+	if err != nil || len(succeeded) != 0 {
+		t.Errorf("ReadResults() of a missing file = %v, %v, expected no results", succeeded, err)
+	}
+}
+
+func TestReadResultsTruncated(t *testing.T) {
+	// An interrupted run may leave its last result half written, which
+	// must not be taken for a failure and proposed again
+	path := writeFile(t, t.TempDir(), "results.ndjson",
+		`{"input":"a","contractId":"contract-a","time":"2024-01-29T09:00:00Z"}`+"\n"+
+			`{"input":"b","contractId":"contr`)
+
+	_, err := ReadResults(path)
+	if err == nil || !strings.Contains(err.Error(), "line 2 is not a complete result") {
+		t.Errorf("ReadResults() of a truncated file error = %v, expected line 2", err)
+	}
+}
+
+func TestProposeAll(t *testing.T) {
+	inputs := []Input{{Name: "a", Body: []byte("a")}, {Name: "b", Body: []byte("b")}, {Name: "c", Body: []byte("c")}}
+
+	propose := func(body []byte) (string, error) {
+		if string(body) == "b" {
+			return "", errors.New("400 Bad Request")
+		}
+		return "contract-" + string(body), nil
+	}
+
+	reported := make(map[string]Result)
+	summary := ProposeAll(inputs, 2, propose, func(result Result) {
+		reported[result.Input] = result
+	})
+
+	if summary.Succeeded != 2 || summary.Failed != 1 || len(reported) != 3 {
+		t.Errorf("ProposeAll() = %+v with %d results, expected 2 succeeded and 1 failed", summary, len(reported))
+	}
+	if reported["a"].ContractId != "contract-a" || !reported["b"].Failed() {
+		t.Errorf("ProposeAll() results = %+v", reported)
+	}
+
+	// A dry run neither succeeds nor fails
+	summary = ProposeAll(inputs, 2, func([]byte) (string, error) {
+		return "", api.ErrDryRun
+	}, func(result Result) {
+		if result.Succeeded() || result.Failed() {
+			t.Errorf("dry run result = %+v", result)
+		}
+	})
+	if summary.DryRun != 3 || summary.Succeeded != 0 || summary.Failed != 0 {
+		t.Errorf("ProposeAll() of a dry run = %+v", summary)
+	}
+}
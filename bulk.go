@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dharm-kapadia/1source-go/api"
+	"github.com/dharm-kapadia/1source-go/batch"
+	"github.com/dharm-kapadia/1source-go/models"
+)
+
+// bulkAction cancels or declines every PROPOSED contract matching the
+// filter, after showing the matched set and asking for confirmation
+// unless assumeYes is set
+func bulkAction(action string, bearer string, filter batch.Filter, concurrency string, assumeYes bool) error {
+	var post func(string, string) (string, error)
+	var path string
+
+	switch action {
+	case "cancel":
+		post, path = api.PostCancelContract, models.ContractCancelPath
+	case "decline":
+		post, path = api.PostDeclineContract, models.ContractDeclinePath
+	default:
+		return usageError(fmt.Sprintf("unknown bulk action '%s', expected cancel or decline", action))
+	}
+
+	if filter.IsEmpty() {
+		return usageError("at least one of --party, --ticker, --trade-date or --venue-ref is required")
+	}
+
+	workers := defaultConcurrency
+	if concurrency != "" {
+		n, err := strconv.Atoi(concurrency)
+		if err != nil || n < 1 {
+			return usageError(fmt.Sprintf("invalid --concurrency value '%s'", concurrency))
+		}
+		workers = n
+	}
+
+	// The API selects the PROPOSED contracts of the party and ticker, the
+	// full filter is still checked on each contract
+	contracts, err := fetchContracts(bearer, api.ListFilter{
+		Status: models.ContractStatusProposed,
+		Party:  filter.Party,
+		Ticker: filter.Ticker,
+	})
+	if err != nil {
+		return err
+	}
+
+	selected := batch.SelectProposed(contracts, filter)
+	if len(selected) == 0 {
+		fmt.Println("No PROPOSED contracts match the filter")
+		return nil
+	}
+
+	printContracts(os.Stdout, selected)
+
+	if !assumeYes && !confirm(os.Stdin, fmt.Sprintf("\n%s %d contracts? [y/N] ", strings.ToUpper(action[:1])+action[1:], len(selected))) {
+		fmt.Println("Aborted, no contracts were changed")
+		return nil
+	}
+
+	log.Printf("Bulk %s of %d contracts with %d workers\n", action, len(selected), workers)
+
+	var summary batch.Summary
+	var failed []batch.Result
+
+	batch.Run(selected, workers, func(contract models.Contract) batch.Result {
+		endPoint := appConfig.Endpoints.Expand(path, contract.ContractId)
+		_, err := post(endPoint, bearer)
+		return batch.NewResult(contract.ContractId, contract.ContractId, err)
+	}, func(result batch.Result) {
+		summary.Add(result)
+		if result.Failed() {
+			failed = append(failed, result)
+		}
+	})
+
+	header := "Bulk " + action + " summary"
+	fmt.Println()
+	fmt.Println(header)
+	fmt.Println(strings.Repeat("=", len(header)))
+	if api.DryRun {
+		fmt.Printf("Matched: %d, Dry run: %d, Failed: %d (no contracts were %s)\n", len(selected), summary.DryRun, summary.Failed, pastTense(action))
+	} else {
+		fmt.Printf("Matched: %d, Succeeded: %d, Failed: %d\n", len(selected), summary.Succeeded, summary.Failed)
+	}
+
+	for _, result := range failed {
+		fmt.Printf("  %s: %s\n", result.ContractId, result.Error)
+	}
+
+	log.Printf("Bulk %s finished. Succeeded: %d, Failed: %d, Dry run: %d\n", action, summary.Succeeded, summary.Failed, summary.DryRun)
+
+	if summary.Failed > 0 {
+		return fmt.Errorf("%d of %d contracts could not be %s", summary.Failed, len(selected), pastTense(action))
+	}
+
+	return nil
+}
+
+// printContracts writes a table of the contracts selected for a bulk action
+func printContracts(w io.Writer, contracts models.Contracts) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CONTRACT ID\tTICKER\tQUANTITY\tTRADE DATE\tVENUE REF\tLENDER\tBORROWER")
+
+	for _, c := range contracts {
+		lender, borrower := c.Parties()
+
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%s\t%s\n", c.ContractId, c.Trade.Instrument.Ticker, c.Trade.Quantity,
+			c.Trade.TradeDate, c.Trade.ExecutionVenue.Platform.VenueRefId, lender, borrower)
+	}
+
+	tw.Flush()
+}
+
+// confirm asks a yes/no question and reports whether the answer was yes
+func confirm(r io.Reader, question string) bool {
+	fmt.Print(question)
+
+	answer, _ := bufio.NewReader(r).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+
+	return answer == "y" || answer == "yes"
+}
+
+// changeContract cancels or declines one contract, after checking that it
+// is in the PROPOSED state
+func changeContract(action string, contractId string, bearer string) error {
+	var post func(string, string) (string, error)
+	var path string
+
+	switch action {
+	case "cancel":
+		post, path = api.PostCancelContract, models.ContractCancelPath
+	case "decline":
+		post, path = api.PostDeclineContract, models.ContractDeclinePath
+	}
+
+	data, err := api.GetEntity(appConfig.Endpoints.Expand(models.ContractPath, contractId), bearer, "1Source Contract")
+	if err != nil {
+		return fmt.Errorf("retrieving contract [%s]: %w", contractId, err)
+	}
+
+	var contract models.Contract
+	if err := json.Unmarshal([]byte(data), &contract); err != nil {
+		return fmt.Errorf("decoding contract [%s]: %w", contractId, err)
+	}
+
+	if contract.ContractStatus != models.ContractStatusProposed {
+		return fmt.Errorf("%w: contract [%s] is %s and cannot be %s", errNotProposed, contractId, contract.ContractStatus, pastTense(action))
+	}
+
+	resp, err := post(appConfig.Endpoints.Expand(path, contractId), bearer)
+	if errors.Is(err, api.ErrDryRun) {
+		fmt.Printf("Dry run: contract was not %s\n", pastTense(action))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Successful: ", resp)
+	return nil
+}
+
+// pastTense returns the past tense of a bulk action
+func pastTense(action string) string {
+	if action == "cancel" {
+		return "canceled"
+	}
+
+	return action + "d"
+}
@@ -0,0 +1,97 @@
+// Package cache keeps the latest entity lists retrieved from the 1Source
+// REST API on local disk, so reports can run without calling the API.
+package cache
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Dir is the directory holding the cached entity lists, one directory
+// per profile
+var Dir = ".1source-cache"
+
+// Meta describes a cached list: the profile and the URL it was read
+// from, whether every page of the list was read, and when it was written
+type Meta struct {
+	Profile  string    `json:"profile"`
+	URL      string    `json:"url"`
+	Complete bool      `json:"complete"`
+	Written  time.Time `json:"written"`
+}
+
+// Path returns the path of the cache file of an entity type of a profile
+func Path(profile string, entity string) string {
+	return filepath.Join(Dir, url.PathEscape(profile), entity+".json")
+}
+
+// metaPath returns the path of the description of a cache file
+func metaPath(profile string, entity string) string {
+	return filepath.Join(Dir, url.PathEscape(profile), entity+".meta.json")
+}
+
+// Write stores the JSON list of an entity type in the cache of the
+// profile of meta, with its description
+func Write(entity string, meta Meta, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(Path(meta.Profile, entity)), 0700); err != nil {
+		return err
+	}
+
+	meta.Written = time.Now().UTC()
+	description, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	// The description goes first: a list without one is never read
+	if err := os.Remove(metaPath(meta.Profile, entity)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := writeFile(Path(meta.Profile, entity), data); err != nil {
+		return err
+	}
+
+	return writeFile(metaPath(meta.Profile, entity), description)
+}
+
+// writeFile writes to a temporary file first so readers never see a
+// partial file
+func writeFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// Read returns the cached JSON list of an entity type of a profile and
+// its description. The error satisfies errors.Is(err, os.ErrNotExist)
+// when the entity type is not cached.
+func Read(profile string, entity string) ([]byte, Meta, error) {
+	var meta Meta
+
+	description, err := os.ReadFile(metaPath(profile, entity))
+	if err != nil {
+		return nil, meta, err
+	}
+	if err := json.Unmarshal(description, &meta); err != nil {
+		return nil, meta, err
+	}
+
+	data, err := os.ReadFile(Path(profile, entity))
+	if err != nil {
+		return nil, meta, err
+	}
+
+	return data, meta, nil
+}
+
+// Exists reports whether the entity type is cached for a profile
+func Exists(profile string, entity string) bool {
+	_, err := os.Stat(metaPath(profile, entity))
+	return err == nil
+}
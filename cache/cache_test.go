@@ -0,0 +1,32 @@
+package cache
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestWriteRead(t *testing.T) {
+	Dir = t.TempDir()
+
+	meta := Meta{Profile: "uat", URL: "https://uat.example.com/v1/ledger/contracts"}
+	if err := Write("contracts", meta, []byte(`[{"contractId":"a"}]`)); err != nil {
+		t.Fatal(err)
+	}
+
+	data, read, err := Read("uat", "contracts")
+	if err != nil || string(data) != `[{"contractId":"a"}]` {
+		t.Fatalf("Read() = %s, %v", data, err)
+	}
+	if read.Profile != "uat" || read.URL != meta.URL || read.Complete || read.Written.IsZero() {
+		t.Errorf("Read() description = %+v, expected a partial list of uat", read)
+	}
+
+	// Lists are kept apart by profile
+	if Exists("prod", "contracts") {
+		t.Error("the contracts of uat are cached for prod")
+	}
+	if _, _, err := Read("prod", "contracts"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Read() of another profile error = %v, expected ErrNotExist", err)
+	}
+}
@@ -0,0 +1,112 @@
+// Package collateral derives the contract and collateral values of a
+// 1Source contract from its price, quantity, margin and rounding terms.
+package collateral
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Rounding modes of the 1Source collateral block
+const (
+	AlwaysUp   = "ALWAYSUP"
+	AlwaysDown = "ALWAYSDOWN"
+)
+
+// epsilon absorbs floating point noise before rounding up or down, so
+// that a value which is already a multiple of the rounding increment is
+// left untouched
+const epsilon = 1e-9
+
+// Terms are the inputs of the collateral calculation. Margin is a
+// percentage of the contract value (102 means 102%). RoundingRule is the
+// increment, in collateral currency units, the collateral value is
+// rounded to; zero rounds to cents.
+type Terms struct {
+	Price        float64
+	Quantity     float64
+	Margin       float64
+	RoundingRule float64
+	RoundingMode string
+}
+
+// Values are the results of the collateral calculation
+type Values struct {
+	ContractValue   float64
+	CollateralValue float64
+}
+
+// Compute derives the contract value from price × quantity and the
+// collateral value from the margin, rounded by the rounding rule and mode
+func Compute(terms Terms) (Values, error) {
+	if terms.Price <= 0 {
+		return Values{}, errors.New("contract price must be greater than zero")
+	}
+
+	if terms.Quantity <= 0 {
+		return Values{}, errors.New("quantity must be greater than zero")
+	}
+
+	contractValue := ContractValue(terms.Price, terms.Quantity)
+
+	collateralValue, err := CollateralValue(contractValue, terms.Margin, terms.RoundingRule, terms.RoundingMode)
+	if err != nil {
+		return Values{}, err
+	}
+
+	return Values{ContractValue: contractValue, CollateralValue: collateralValue}, nil
+}
+
+// ContractValue returns price × quantity rounded to cents
+func ContractValue(price float64, quantity float64) float64 {
+	return RoundCents(price * quantity)
+}
+
+// CollateralValue applies the margin to the contract value and rounds the
+// result according to the rounding rule and mode
+func CollateralValue(contractValue float64, margin float64, rule float64, mode string) (float64, error) {
+	if margin <= 0 {
+		return 0, errors.New("margin must be greater than zero")
+	}
+
+	return Round(RoundCents(contractValue*margin/100), rule, mode)
+}
+
+// Round rounds value to a multiple of rule. ALWAYSUP rounds towards
+// positive infinity, ALWAYSDOWN towards negative infinity and an empty
+// mode to the nearest multiple. A rule of zero rounds to cents.
+func Round(value float64, rule float64, mode string) (float64, error) {
+	if rule < 0 {
+		return 0, fmt.Errorf("rounding rule %v must not be negative", rule)
+	}
+
+	if rule == 0 {
+		rule = 0.01
+	}
+
+	steps := value / rule
+
+	switch mode {
+	case AlwaysUp:
+		steps = math.Ceil(steps - epsilon)
+	case AlwaysDown:
+		steps = math.Floor(steps + epsilon)
+	case "":
+		steps = math.Round(steps)
+	default:
+		return 0, fmt.Errorf("unknown rounding mode '%s'", mode)
+	}
+
+	return RoundCents(steps * rule), nil
+}
+
+// Equal reports whether two monetary values are equal to the cent
+func Equal(a float64, b float64) bool {
+	return math.Abs(a-b) < 0.005
+}
+
+// RoundCents rounds a monetary value to two decimal places
+func RoundCents(value float64) float64 {
+	return math.Round(value*100) / 100
+}
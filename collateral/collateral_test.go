@@ -0,0 +1,109 @@
+package collateral
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/dharm-kapadia/1source-go/models"
+)
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		value    float64
+		rule     float64
+		mode     string
+		expected float64
+	}{
+		{22610340, 10, AlwaysUp, 22610340},
+		{22610341, 10, AlwaysUp, 22610350},
+		{22610349, 10, AlwaysDown, 22610340},
+		{22610345, 10, "", 22610350},
+		{22610344.99, 10, "", 22610340},
+		{1.1 * 3, 0.1, AlwaysUp, 3.3},
+		{1.1 * 3, 0.1, AlwaysDown, 3.3},
+		{102.004, 0, AlwaysUp, 102.01},
+		{102.006, 0, AlwaysDown, 102},
+		{-15, 10, AlwaysUp, -10},
+		{-15, 10, AlwaysDown, -20},
+	}
+
+	for _, tt := range tests {
+		rounded, err := Round(tt.value, tt.rule, tt.mode)
+		if err != nil || rounded != tt.expected {
+			t.Errorf("Round(%v, %v, %q) = %v, %v, expected %v", tt.value, tt.rule, tt.mode, rounded, err, tt.expected)
+		}
+	}
+
+	if _, err := Round(100, -1, AlwaysUp); err == nil {
+		t.Error("Round() with a negative rule succeeded")
+	}
+	if _, err := Round(100, 10, "NEAREST"); err == nil {
+		t.Error("Round() with an unknown mode succeeded")
+	}
+}
+
+func TestCompute(t *testing.T) {
+	tests := []struct {
+		terms      Terms
+		contract   float64
+		collateral float64
+	}{
+		// The sample proposal: 150000 JPM at 147.78, 102% rounded up to 10
+		{Terms{Price: 147.78, Quantity: 150000, Margin: 102, RoundingRule: 10, RoundingMode: AlwaysUp}, 22167000, 22610340},
+		{Terms{Price: 147.78, Quantity: 150000, Margin: 105, RoundingRule: 1000, RoundingMode: AlwaysDown}, 22167000, 23275000},
+		{Terms{Price: 33.333, Quantity: 3, Margin: 100}, 100, 100},
+		{Terms{Price: 10.005, Quantity: 1, Margin: 102}, 10.01, 10.21},
+	}
+
+	for _, tt := range tests {
+		values, err := Compute(tt.terms)
+		if err != nil || values.ContractValue != tt.contract || values.CollateralValue != tt.collateral {
+			t.Errorf("Compute(%+v) = %+v, %v, expected %v and %v", tt.terms, values, err, tt.contract, tt.collateral)
+		}
+	}
+
+	for _, terms := range []Terms{
+		{Quantity: 100, Margin: 102},
+		{Price: 10, Margin: 102},
+		{Price: 10, Quantity: 100},
+	} {
+		if _, err := Compute(terms); err == nil {
+			t.Errorf("Compute(%+v) succeeded", terms)
+		}
+	}
+}
+
+func TestFillProposal(t *testing.T) {
+	sample, err := os.ReadFile("../proposed_trade.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The sample proposal carries the expected values already
+	if out, filled, err := FillProposal(sample); err != nil || filled || string(out) != string(sample) {
+		t.Errorf("FillProposal() of a complete proposal = %v, %v, expected it unchanged", filled, err)
+	}
+
+	body := []byte(`{"trade":{"instrument":{"price":{"value":147.78}},"quantity":150000,"venueRefId":"x",
+		"collateral":{"contractValue":0,"collateralValue":1,"margin":102,"roundingRule":10,"roundingMode":"ALWAYSUP"}}}`)
+
+	out, filled, err := FillProposal(body)
+	if err != nil || !filled {
+		t.Fatalf("FillProposal() = %v, %v", filled, err)
+	}
+
+	var proposal models.ContractProposal
+	if err := json.Unmarshal(out, &proposal); err != nil {
+		t.Fatal(err)
+	}
+	block := proposal.Trade.Collateral
+	if block.ContractPrice != 147.78 || block.ContractValue != 22167000 || block.CollateralValue != 1 {
+		t.Errorf("FillProposal() collateral = %+v, expected the missing values filled in only", block)
+	}
+
+	var doc map[string]map[string]any
+	if err := json.Unmarshal(out, &doc); err != nil || doc["trade"]["venueRefId"] != "x" {
+		t.Errorf("FillProposal() dropped a field unknown to the models: %s", out)
+	}
+}
@@ -0,0 +1,118 @@
+// Package collateral derives the contract and collateral values of a
+// 1Source contract from its price, quantity, margin and rounding terms.
+package collateral
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/dharm-kapadia/1source-go/models"
+)
+
+// ProposalTerms returns the collateral terms of a contract proposal. The
+// contract price falls back to the instrument price when the collateral
+// block does not carry one.
+func ProposalTerms(proposal *models.ContractProposal) Terms {
+	trade := proposal.Trade
+
+	price := trade.Collateral.ContractPrice
+	if price == 0 {
+		price = trade.Instrument.Price.Value
+	}
+
+	return Terms{
+		Price:        price,
+		Quantity:     float64(trade.Quantity),
+		Margin:       trade.Collateral.Margin,
+		RoundingRule: float64(trade.Collateral.RoundingRule),
+		RoundingMode: trade.Collateral.RoundingMode,
+	}
+}
+
+// FillProposal fills in the contractPrice, contractValue and
+// collateralValue of a JSON contract proposal when they are missing or
+// zero. Values already present are left unchanged. It returns the
+// updated proposal and whether anything was filled in.
+func FillProposal(body []byte) ([]byte, bool, error) {
+	var proposal models.ContractProposal
+	if err := json.Unmarshal(body, &proposal); err != nil {
+		return nil, false, err
+	}
+
+	terms := ProposalTerms(&proposal)
+	values, err := Compute(terms)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Work on a generic document so fields unknown to the models survive
+	var doc map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, false, err
+	}
+
+	trade, ok := doc["trade"].(map[string]any)
+	if !ok {
+		return nil, false, errors.New("proposal has no trade")
+	}
+
+	block, ok := trade["collateral"].(map[string]any)
+	if !ok {
+		block = make(map[string]any)
+		trade["collateral"] = block
+	}
+
+	filled := false
+	fill := func(key string, value float64) {
+		if isMissing(block[key]) {
+			block[key] = json.Number(strconv.FormatFloat(value, 'f', -1, 64))
+			filled = true
+		}
+	}
+
+	fill("contractPrice", terms.Price)
+	fill("contractValue", values.ContractValue)
+	fill("collateralValue", values.CollateralValue)
+
+	if !filled {
+		return body, false, nil
+	}
+
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, false, err
+	}
+
+	return out, true, nil
+}
+
+// isMissing reports whether a JSON value is absent, null or zero
+func isMissing(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case json.Number:
+		f, err := v.Float64()
+		return err == nil && f == 0
+	}
+
+	return false
+}
+
+// ContractTerms returns the collateral terms of a contract revalued at
+// the given price
+func ContractTerms(contract models.Contract, price float64) Terms {
+	block := contract.Trade.Collateral
+
+	return Terms{
+		Price:        price,
+		Quantity:     float64(contract.Trade.Quantity),
+		Margin:       block.Margin,
+		RoundingRule: float64(block.RoundingRule),
+		RoundingMode: block.RoundingMode,
+	}
+}
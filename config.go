@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dharm-kapadia/1source-go/utils"
+)
+
+// checkConfig validates a configuration file and the selected profile,
+// printing every problem found. It reports whether the file is valid.
+func checkConfig(filename string, profile string) bool {
+	warnings, errs := utils.CheckConfig(filename, profile)
+
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+
+	if len(errs) > 0 {
+		fmt.Fprintf(os.Stderr, "'%s' has %d problem(s):\n", filename, len(errs))
+		for _, e := range errs {
+			fmt.Fprintf(os.Stderr, "  %s\n", e)
+		}
+		return false
+	}
+
+	if profile == "" {
+		profile = utils.DefaultProfile
+	}
+	fmt.Printf("'%s' is valid, profile '%s'\n", filename, profile)
+
+	return true
+}
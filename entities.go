@@ -0,0 +1,22 @@
+package main
+
+// entityHeaders are the headers printed above the entity lists
+var entityHeaders = map[string]string{
+	"events":     "1Source Events",
+	"parties":    "1Source Parties",
+	"agreements": "1Source Trade Agreements",
+	"contracts":  "1Source Contracts",
+	"rerates":    "1Source Rerates",
+	"returns":    "1Source Returns",
+	"recalls":    "1Source Recalls",
+	"buyins":     "1Source Buyins",
+}
+
+// entityHeader returns the header printed above an entity list
+func entityHeader(entity string) string {
+	if header, ok := entityHeaders[entity]; ok {
+		return header
+	}
+
+	return "1Source " + entity
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/dharm-kapadia/1source-go/api"
+	"github.com/dharm-kapadia/1source-go/utils"
+)
+
+// Exit codes of the command line application, documented in the README
+const (
+	exitOK       = 0 // success
+	exitFailure  = 1 // the command ran and failed, such as an invalid proposal or failed batch items
+	exitUsage    = 2 // unknown command, option or entity
+	exitConfig   = 3 // the configuration file cannot be read or is not valid
+	exitAuth     = 4 // the login failed or the API refused the credentials
+	exitNotFound = 5 // the entity does not exist
+	exitConflict = 6 // the entity is not in a state allowing the action
+	exitServer   = 7 // the API answered with an unexpected status
+	exitNetwork  = 8 // the API or KeyCloak cannot be reached
+)
+
+// errNotProposed is returned when a contract cannot be canceled or
+// declined as it is not in the PROPOSED state
+var errNotProposed = errors.New("contract is not in PROPOSED state")
+
+// usageError is an error in the command line
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+// exitCode returns the exit code of an error
+func exitCode(err error) int {
+	var usageErr usageError
+	var configErrs utils.ConfigErrors
+	var netErr net.Error
+	var pathErr *fs.PathError
+	var statusErr *api.StatusError
+
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &usageErr):
+		return exitUsage
+	case errors.As(err, &configErrs):
+		return exitConfig
+	case errors.Is(err, api.ErrNotRecorded):
+		return exitFailure
+	// A file error carries an errno, which also satisfies net.Error
+	case errors.As(err, &pathErr):
+		return exitFailure
+	case errors.As(err, &netErr), errors.Is(err, api.ErrUnreachable):
+		return exitNetwork
+	case errors.Is(err, api.ErrAuth):
+		return exitAuth
+	case errors.Is(err, errNotProposed):
+		return exitConflict
+	case errors.As(err, &statusErr):
+		switch statusErr.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return exitAuth
+		case http.StatusNotFound:
+			return exitNotFound
+		case http.StatusConflict:
+			return exitConflict
+		}
+		return exitServer
+	}
+
+	return exitFailure
+}
+
+// fail writes an error to stderr and the log, and exits with the exit
+// code of the error
+func fail(message string, err error) {
+	fmt.Fprintf(os.Stderr, "%s: %s\n", message, err)
+	slog.Error(message, "error", err)
+	os.Exit(exitCode(err))
+}
+
+// failUsage writes a usage error to stderr and the log, and exits
+func failUsage(format string, args ...any) {
+	message := fmt.Sprintf(format, args...)
+	fmt.Fprintln(os.Stderr, message)
+	fmt.Fprintln(os.Stderr, "Run with --help for usage")
+	slog.Error(message)
+	os.Exit(exitUsage)
+}
+
+// exitOnError exits with the exit code of err, once it has been reported
+func exitOnError(err error) {
+	if err != nil {
+		os.Exit(exitCode(err))
+	}
+}
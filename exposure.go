@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dharm-kapadia/1source-go/api"
+	"github.com/dharm-kapadia/1source-go/cache"
+	"github.com/dharm-kapadia/1source-go/exposure"
+	"github.com/dharm-kapadia/1source-go/models"
+)
+
+// exposureReport aggregates the open contracts by the comma separated
+// dimensions and prints the result as a table, or writes it to csvFile
+// when given. The contracts are read from the local cache when present,
+// unless refresh is set.
+func exposureReport(dimList string, bearer string, self string, csvFile string, refresh bool) error {
+	dims, err := exposure.ParseDimensions(dimList)
+	if err != nil {
+		return err
+	}
+
+	contracts, err := loadContracts(bearer, !refresh)
+	if err != nil {
+		return err
+	}
+
+	rows := exposure.Aggregate(contracts, dims, self)
+	log.Printf("Exposure by %s: %d rows\n", strings.Join(dims, ", "), len(rows))
+
+	if csvFile != "" {
+		file, err := os.Create(csvFile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		if err := exposure.WriteCSV(file, dims, rows); err != nil {
+			return err
+		}
+
+		fmt.Printf("Exposure written to '%s'\n", csvFile)
+		return nil
+	}
+
+	header := "Exposure by " + strings.Join(dims, ", ")
+	fmt.Println(header)
+	fmt.Println(strings.Repeat("=", len(header)))
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(dims, "\t"))+"\tCONTRACTS\tQUANTITY\tCONTRACT VALUE\tCOLLATERAL VALUE\tWEIGHTED RATE\t")
+	for _, r := range rows {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.2f\t%.2f\t%.4f\t\n", strings.Join(r.Keys, "\t"), r.Contracts, r.Quantity,
+			r.ContractValue, r.CollateralValue, r.Rate)
+	}
+	tw.Flush()
+
+	return nil
+}
+
+// loadContracts returns the contracts from the local cache of the profile
+// when useCache is set and the cache holds them, or the open contracts
+// from the 1Source REST API otherwise. A cached list which is only a
+// first page, or was read from another URL, is refused.
+func loadContracts(bearer string, useCache bool) (models.Contracts, error) {
+	if !useCache || !cache.Exists(appConfig.Profile, "contracts") {
+		return fetchContracts(bearer, api.ListFilter{Status: models.ContractStatusOpen})
+	}
+
+	data, meta, err := cache.Read(appConfig.Profile, "contracts")
+	if err != nil {
+		return nil, err
+	}
+
+	if !meta.Complete {
+		return nil, fmt.Errorf("the contracts cached for profile '%s' are only the first page of the list, "+
+			"run '-g contracts --all' or use --refresh", meta.Profile)
+	}
+	if meta.URL != appConfig.Endpoints.Contracts {
+		return nil, fmt.Errorf("the contracts cached for profile '%s' were read from '%s', not '%s', use --refresh",
+			meta.Profile, meta.URL, appConfig.Endpoints.Contracts)
+	}
+
+	written := meta.Written.Local().Format("2006-01-02 15:04:05")
+	fmt.Printf("Using contracts of profile '%s' cached at %s from '%s'\n\n", meta.Profile, written, meta.URL)
+	log.Printf("Using contracts of profile '%s' cached at %s\n", meta.Profile, written)
+
+	var contracts models.Contracts
+	if err := json.Unmarshal(data, &contracts); err != nil {
+		return nil, fmt.Errorf("decoding cached contracts: %w", err)
+	}
+
+	return contracts, nil
+}
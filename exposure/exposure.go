@@ -0,0 +1,185 @@
+// Package exposure aggregates open 1Source contracts by counterparty,
+// instrument, currency and venue.
+package exposure
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dharm-kapadia/1source-go/collateral"
+	"github.com/dharm-kapadia/1source-go/models"
+)
+
+// Dimensions open contracts can be grouped by
+const (
+	Counterparty = "counterparty"
+	Instrument   = "instrument"
+	Currency     = "currency"
+	Venue        = "venue"
+)
+
+// Dimensions lists every dimension, in the default grouping order
+var Dimensions = []string{Counterparty, Instrument, Currency, Venue}
+
+// Row is the exposure of one group of open contracts. Keys holds the
+// group value of each requested dimension, in order. Rate is the average
+// rate in percent weighted by contract value.
+type Row struct {
+	Keys            []string
+	Contracts       int
+	Quantity        uint64
+	ContractValue   float64
+	CollateralValue float64
+	Rate            float64
+}
+
+// ParseDimensions parses a comma separated list of dimensions. An empty
+// list selects every dimension. Currency is added last when missing, as
+// values in different currencies cannot be summed.
+func ParseDimensions(list string) ([]string, error) {
+	if list == "" || list == "all" {
+		return Dimensions, nil
+	}
+
+	var dims []string
+	for _, d := range strings.Split(list, ",") {
+		d = strings.ToLower(strings.TrimSpace(d))
+
+		valid := false
+		for _, known := range Dimensions {
+			valid = valid || d == known
+		}
+		if !valid {
+			return nil, fmt.Errorf("unknown exposure dimension '%s', expected one of %s", d, strings.Join(Dimensions, ", "))
+		}
+
+		dims = append(dims, d)
+	}
+
+	for _, d := range dims {
+		if d == Currency {
+			return dims, nil
+		}
+	}
+
+	return append(dims, Currency), nil
+}
+
+// Aggregate groups the OPEN contracts by the given dimensions and totals
+// their quantity, contract value and collateral value. The dimensions
+// must include Currency, see ParseDimensions. self is the
+// partyId used to name counterparties, see models.Contract.Counterparty.
+// Rows are sorted by their keys.
+func Aggregate(contracts models.Contracts, dims []string, self string) []Row {
+	rows := make(map[string]*Row)
+	weighted := make(map[string]float64)
+
+	for _, c := range contracts {
+		if c.ContractStatus != models.ContractStatusOpen {
+			continue
+		}
+
+		keys := make([]string, len(dims))
+		for i, d := range dims {
+			keys[i] = key(c, d, self)
+		}
+		id := strings.Join(keys, "\x00")
+
+		row, ok := rows[id]
+		if !ok {
+			row = &Row{Keys: keys}
+			rows[id] = row
+		}
+
+		block := c.Trade.Collateral
+		row.Contracts++
+		row.Quantity += uint64(c.Trade.Quantity)
+		row.ContractValue = collateral.RoundCents(row.ContractValue + block.ContractValue)
+		row.CollateralValue = collateral.RoundCents(row.CollateralValue + block.CollateralValue)
+		weighted[id] += block.ContractValue * rate(c)
+	}
+
+	result := make([]Row, 0, len(rows))
+	for id, row := range rows {
+		if row.ContractValue != 0 {
+			row.Rate = weighted[id] / row.ContractValue
+		}
+		result = append(result, *row)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return strings.Join(result[i].Keys, "\x00") < strings.Join(result[j].Keys, "\x00")
+	})
+
+	return result
+}
+
+// key returns the group value of a contract for a dimension
+func key(c models.Contract, dim string, self string) string {
+	trade := c.Trade
+
+	switch dim {
+	case Counterparty:
+		return c.Counterparty(self)
+	case Instrument:
+		if trade.Instrument.Ticker != "" {
+			return trade.Instrument.Ticker
+		}
+		return trade.Instrument.Isin
+	case Currency:
+		return trade.Collateral.Currency
+	case Venue:
+		if trade.ExecutionVenue.Platform.VenueName != "" {
+			return trade.ExecutionVenue.Platform.VenueName
+		}
+		return trade.ExecutionVenue.VenueType
+	}
+
+	return ""
+}
+
+// rate returns the rebate rate of a cash collateralized contract, or the
+// fee rate of another contract, in percent
+func rate(c models.Contract) float64 {
+	fixed := c.Trade.Rate.Rebate.Fixed
+	if c.Trade.Collateral.Type != "CASH" {
+		fixed = c.Trade.Rate.Fee
+	}
+	if fixed.EffectiveRate != 0 {
+		return fixed.EffectiveRate
+	}
+
+	return fixed.BaseRate
+}
+
+// WriteCSV writes one CSV record per row, preceded by a header naming the
+// dimensions
+func WriteCSV(w io.Writer, dims []string, rows []Row) error {
+	writer := csv.NewWriter(w)
+
+	header := append(append([]string(nil), dims...), "contracts", "quantity", "contractValue", "collateralValue", "weightedRate")
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, r := range rows {
+		record := append(append([]string(nil), r.Keys...),
+			strconv.Itoa(r.Contracts),
+			strconv.FormatUint(r.Quantity, 10),
+			strconv.FormatFloat(r.ContractValue, 'f', 2, 64),
+			strconv.FormatFloat(r.CollateralValue, 'f', 2, 64),
+			strconv.FormatFloat(r.Rate, 'f', 6, 64),
+		)
+
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
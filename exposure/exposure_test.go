@@ -0,0 +1,81 @@
+package exposure
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/dharm-kapadia/1source-go/models"
+)
+
+func TestParseDimensionsAddsCurrency(t *testing.T) {
+	tests := []struct {
+		list     string
+		expected []string
+	}{
+		{"counterparty", []string{Counterparty, Currency}},
+		{"instrument, venue", []string{Instrument, Venue, Currency}},
+		{"currency,instrument", []string{Currency, Instrument}},
+		{"all", Dimensions},
+	}
+
+	for _, tt := range tests {
+		dims, err := ParseDimensions(tt.list)
+		if err != nil || !reflect.DeepEqual(dims, tt.expected) {
+			t.Errorf("ParseDimensions(%q) = %v, %v, expected %v", tt.list, dims, err, tt.expected)
+		}
+	}
+
+	if _, err := ParseDimensions("desk"); err == nil {
+		t.Error("ParseDimensions(\"desk\") succeeded")
+	}
+}
+
+func TestAggregateSeparatesCurrencies(t *testing.T) {
+	var contracts models.Contracts
+	err := json.Unmarshal([]byte(`[
+		{"contractStatus":"OPEN","trade":{"instrument":{"ticker":"IBM"},"quantity":100,
+			"collateral":{"contractValue":1000,"collateralValue":1020,"currency":"USD"}}},
+		{"contractStatus":"OPEN","trade":{"instrument":{"ticker":"IBM"},"quantity":50,
+			"collateral":{"contractValue":500,"collateralValue":510,"currency":"EUR"}}},
+		{"contractStatus":"OPEN","trade":{"instrument":{"ticker":"IBM"},"quantity":10,
+			"collateral":{"contractValue":100,"collateralValue":102,"currency":"USD"}}},
+		{"contractStatus":"PROPOSED","trade":{"instrument":{"ticker":"IBM"},"quantity":7,
+			"collateral":{"contractValue":70,"collateralValue":71,"currency":"USD"}}}
+	]`), &contracts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dims, _ := ParseDimensions("instrument")
+	rows := Aggregate(contracts, dims, "")
+
+	expected := []Row{
+		{Keys: []string{"IBM", "EUR"}, Contracts: 1, Quantity: 50, ContractValue: 500, CollateralValue: 510},
+		{Keys: []string{"IBM", "USD"}, Contracts: 2, Quantity: 110, ContractValue: 1100, CollateralValue: 1122},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Aggregate() = %+v, expected %+v", rows, expected)
+	}
+}
+
+func TestAggregateWeightedRate(t *testing.T) {
+	var contracts models.Contracts
+	err := json.Unmarshal([]byte(`[
+		{"contractStatus":"OPEN","trade":{"instrument":{"ticker":"IBM"},"quantity":100,
+			"rate":{"rebate":{"fixed":{"baseRate":5,"effectiveRate":4}}},
+			"collateral":{"type":"CASH","contractValue":3000,"collateralValue":3060,"currency":"USD"}}},
+		{"contractStatus":"OPEN","trade":{"instrument":{"ticker":"IBM"},"quantity":50,
+			"rate":{"rebate":{"fixed":{"baseRate":5}},"fee":{"baseRate":0.5}},
+			"collateral":{"type":"NONCASH","contractValue":1000,"collateralValue":1050,"currency":"USD"}}}
+	]`), &contracts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Cash contracts weigh their rebate rate, others their fee rate
+	rows := Aggregate(contracts, []string{Currency}, "")
+	if len(rows) != 1 || rows[0].Rate != (3000*4+1000*0.5)/4000 {
+		t.Errorf("Aggregate() = %+v, expected a weighted rate of 3.125", rows)
+	}
+}
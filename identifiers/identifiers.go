@@ -0,0 +1,272 @@
+// Package identifiers validates the security and entity identifiers
+// carried by 1Source contracts: CUSIP, ISIN, SEDOL, FIGI, LEI and BIC.
+package identifiers
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Kinds of identifiers
+const (
+	CUSIP = "CUSIP"
+	ISIN  = "ISIN"
+	SEDOL = "SEDOL"
+	FIGI  = "FIGI"
+	LEI   = "LEI"
+	BIC   = "BIC"
+)
+
+// Validate checks value as an identifier of the given kind
+func Validate(kind string, value string) error {
+	switch kind {
+	case CUSIP:
+		return ValidateCUSIP(value)
+	case ISIN:
+		return ValidateISIN(value)
+	case SEDOL:
+		return ValidateSEDOL(value)
+	case FIGI:
+		return ValidateFIGI(value)
+	case LEI:
+		return ValidateLEI(value)
+	case BIC:
+		return ValidateBIC(value)
+	default:
+		return fmt.Errorf("unknown identifier kind '%s'", kind)
+	}
+}
+
+// ValidateCUSIP checks the length, characters and check digit of a CUSIP
+func ValidateCUSIP(cusip string) error {
+	if len(cusip) != 9 {
+		return fmt.Errorf("CUSIP '%s' must be 9 characters long", cusip)
+	}
+
+	sum := 0
+	for i := 0; i < 8; i++ {
+		v, ok := cusipValue(cusip[i])
+		if !ok {
+			return fmt.Errorf("CUSIP '%s' has an invalid character '%c'", cusip, cusip[i])
+		}
+
+		// Every second character is doubled
+		if i%2 == 1 {
+			v *= 2
+		}
+		sum += v/10 + v%10
+	}
+
+	return checkDigit("CUSIP", cusip, cusip[8], (10-sum%10)%10)
+}
+
+// cusipValue returns the value of a CUSIP character
+func cusipValue(c byte) (int, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0'), true
+	case c >= 'A' && c <= 'Z':
+		return int(c-'A') + 10, true
+	case c == '*':
+		return 36, true
+	case c == '@':
+		return 37, true
+	case c == '#':
+		return 38, true
+	}
+
+	return 0, false
+}
+
+// ValidateISIN checks the country prefix, characters and Luhn check
+// digit of an ISIN
+func ValidateISIN(isin string) error {
+	if len(isin) != 12 {
+		return fmt.Errorf("ISIN '%s' must be 12 characters long", isin)
+	}
+
+	if !isUpper(isin[0]) || !isUpper(isin[1]) {
+		return fmt.Errorf("ISIN '%s' must start with a two letter country code", isin)
+	}
+
+	// Expand letters to two digit numbers (A=10 ... Z=35)
+	var digits strings.Builder
+	for i := 0; i < 11; i++ {
+		c := isin[i]
+		switch {
+		case isDigit(c):
+			digits.WriteByte(c)
+		case isUpper(c):
+			digits.WriteString(fmt.Sprint(int(c-'A') + 10))
+		default:
+			return fmt.Errorf("ISIN '%s' has an invalid character '%c'", isin, c)
+		}
+	}
+
+	// Luhn: double every second digit starting from the rightmost one
+	s := digits.String()
+	sum := 0
+	for i := len(s) - 1; i >= 0; i-- {
+		v := int(s[i] - '0')
+		if (len(s)-1-i)%2 == 0 {
+			v *= 2
+		}
+		sum += v/10 + v%10
+	}
+
+	return checkDigit("ISIN", isin, isin[11], (10-sum%10)%10)
+}
+
+// ValidateSEDOL checks the characters and weighted check digit of a SEDOL
+func ValidateSEDOL(sedol string) error {
+	if len(sedol) != 7 {
+		return fmt.Errorf("SEDOL '%s' must be 7 characters long", sedol)
+	}
+
+	weights := []int{1, 3, 1, 7, 3, 9}
+	sum := 0
+	for i, w := range weights {
+		c := sedol[i]
+
+		var v int
+		switch {
+		case isDigit(c):
+			v = int(c - '0')
+		case isUpper(c) && !strings.ContainsRune("AEIOU", rune(c)):
+			v = int(c-'A') + 10
+		default:
+			return fmt.Errorf("SEDOL '%s' has an invalid character '%c'", sedol, c)
+		}
+
+		sum += v * w
+	}
+
+	return checkDigit("SEDOL", sedol, sedol[6], (10-sum%10)%10)
+}
+
+// ValidateFIGI checks the format of a FIGI: two consonant prefix (not one
+// of the reserved ISIN country prefixes), 'G' as the third character,
+// eight consonants or digits and a numeric check character
+func ValidateFIGI(figi string) error {
+	if len(figi) != 12 {
+		return fmt.Errorf("FIGI '%s' must be 12 characters long", figi)
+	}
+
+	for i := 0; i < 11; i++ {
+		if i == 2 {
+			continue
+		}
+
+		c := figi[i]
+		if !(isDigit(c) && i > 2) && !isConsonant(c) {
+			return fmt.Errorf("FIGI '%s' has an invalid character '%c'", figi, c)
+		}
+	}
+
+	switch figi[:2] {
+	case "BS", "BM", "GG", "GB", "GH", "KY", "VG":
+		return fmt.Errorf("FIGI '%s' must not start with '%s'", figi, figi[:2])
+	}
+
+	if figi[2] != 'G' {
+		return fmt.Errorf("FIGI '%s' must have 'G' as the third character", figi)
+	}
+
+	if !isDigit(figi[11]) {
+		return fmt.Errorf("FIGI '%s' must end with a check digit", figi)
+	}
+
+	return nil
+}
+
+// ValidateLEI checks the characters and ISO 17442 mod-97 check digits of
+// a Legal Entity Identifier
+func ValidateLEI(lei string) error {
+	if len(lei) != 20 {
+		return fmt.Errorf("LEI '%s' must be 20 characters long", lei)
+	}
+
+	var digits strings.Builder
+	for i := 0; i < len(lei); i++ {
+		c := lei[i]
+		switch {
+		case isDigit(c):
+			digits.WriteByte(c)
+		case isUpper(c):
+			digits.WriteString(fmt.Sprint(int(c-'A') + 10))
+		default:
+			return fmt.Errorf("LEI '%s' has an invalid character '%c'", lei, c)
+		}
+	}
+
+	if !isDigit(lei[18]) || !isDigit(lei[19]) {
+		return fmt.Errorf("LEI '%s' must end with two check digits", lei)
+	}
+
+	n, _ := new(big.Int).SetString(digits.String(), 10)
+	if new(big.Int).Mod(n, big.NewInt(97)).Int64() != 1 {
+		return fmt.Errorf("LEI '%s' has invalid check digits", lei)
+	}
+
+	return nil
+}
+
+// ValidateBIC checks the structure of an ISO 9362 BIC: four letter
+// institution code, two letter country code, two character location code
+// and an optional three character branch code
+func ValidateBIC(bic string) error {
+	if len(bic) != 8 && len(bic) != 11 {
+		return fmt.Errorf("BIC '%s' must be 8 or 11 characters long", bic)
+	}
+
+	for i := 0; i < len(bic); i++ {
+		c := bic[i]
+
+		if i < 6 && !isUpper(c) {
+			return fmt.Errorf("BIC '%s' must start with a four letter institution code and a two letter country code", bic)
+		}
+
+		if i >= 6 && !isUpper(c) && !isDigit(c) {
+			return fmt.Errorf("BIC '%s' has an invalid character '%c'", bic, c)
+		}
+	}
+
+	return nil
+}
+
+// CheckISINAndCUSIP checks that an ISIN embeds the given CUSIP, as ISINs
+// issued for CUSIP numbered securities do
+func CheckISINAndCUSIP(isin string, cusip string) error {
+	if len(isin) != 12 || len(cusip) != 9 {
+		return fmt.Errorf("ISIN '%s' and CUSIP '%s' cannot be compared", isin, cusip)
+	}
+
+	if isin[2:11] != cusip {
+		return fmt.Errorf("ISIN '%s' does not match CUSIP '%s'", isin, cusip)
+	}
+
+	return nil
+}
+
+// checkDigit compares the check character of an identifier with the
+// expected check digit
+func checkDigit(kind string, value string, actual byte, expected int) error {
+	if int(actual-'0') != expected || !isDigit(actual) {
+		return fmt.Errorf("%s '%s' has an invalid check digit, expected %d", kind, value, expected)
+	}
+
+	return nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isUpper(c byte) bool {
+	return c >= 'A' && c <= 'Z'
+}
+
+func isConsonant(c byte) bool {
+	return isUpper(c) && !strings.ContainsRune("AEIOU", rune(c))
+}
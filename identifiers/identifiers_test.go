@@ -0,0 +1,71 @@
+package identifiers
+
+import "testing"
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		kind  string
+		value string
+		valid bool
+	}{
+		{CUSIP, "037833100", true},
+		{CUSIP, "459200101", true},
+		{CUSIP, "38259P508", true},
+		{CUSIP, "037833101", false},
+		{CUSIP, "03783310", false},
+		{CUSIP, "03783-100", false},
+
+		{ISIN, "US0378331005", true},
+		{ISIN, "US4592001014", true},
+		{ISIN, "GB0002634946", true},
+		{ISIN, "AU0000XVGZA3", true},
+		{ISIN, "US0378331006", false},
+		{ISIN, "120378331005", false},
+		{ISIN, "US037833100", false},
+		{ISIN, "US03783310-5", false},
+
+		{SEDOL, "0263494", true},
+		{SEDOL, "B0YBKJ7", true},
+		{SEDOL, "2046251", true},
+		{SEDOL, "0263495", false},
+		{SEDOL, "B0YAKJ7", false},
+		{SEDOL, "026349", false},
+
+		{FIGI, "BBG000BLNNH6", true},
+		{FIGI, "BBG000B9XRY4", true},
+		{FIGI, "GBG000BLNNH6", false},
+		{FIGI, "BBX000BLNNH6", false},
+		{FIGI, "BBG000BLNNHX", false},
+		{FIGI, "BBG000BLANH6", false},
+
+		{LEI, "HWUPKR0MPOU8FGXBT394", true},
+		{LEI, "5493001KJTIIGC8Y1R12", true},
+		{LEI, "HWUPKR0MPOU8FGXBT395", false},
+		{LEI, "HWUPKR0MPOU8FGXBT3A4", false},
+		{LEI, "HWUPKR0MPOU8FGXBT39", false},
+
+		{BIC, "DEUTDEFF", true},
+		{BIC, "DEUTDEFF500", true},
+		{BIC, "DEUT1EFF", false},
+		{BIC, "DEUTDEF", false},
+		{BIC, "DEUTDEFF50-", false},
+
+		{"WKN", "840400", false},
+	}
+
+	for _, tt := range tests {
+		err := Validate(tt.kind, tt.value)
+		if (err == nil) != tt.valid {
+			t.Errorf("Validate(%s, %q) = %v, expected valid = %v", tt.kind, tt.value, err, tt.valid)
+		}
+	}
+}
+
+func TestCheckISINAndCUSIP(t *testing.T) {
+	if err := CheckISINAndCUSIP("US0378331005", "037833100"); err != nil {
+		t.Errorf("CheckISINAndCUSIP() of matching identifiers = %v", err)
+	}
+	if err := CheckISINAndCUSIP("US4592001014", "037833100"); err == nil {
+		t.Error("CheckISINAndCUSIP() of different securities succeeded")
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/user"
+	"path"
+	"sync"
+
+	"github.com/dharm-kapadia/1source-go/api"
+	"github.com/dharm-kapadia/1source-go/audit"
+	"github.com/dharm-kapadia/1source-go/models"
+	"github.com/dharm-kapadia/1source-go/redact"
+)
+
+// operations names the calls made by api.Post in the audit journal
+var operations = map[string]string{
+	api.ActionPropose: "propose",
+	api.ActionCancel:  "cancel",
+	api.ActionDecline: "decline",
+}
+
+// auditErrs holds the errors writing the audit journal, which fail the
+// command once it is done
+var auditErrs struct {
+	sync.Mutex
+	errs []error
+}
+
+// auditCall records a mutating call in the audit journal. It is set as
+// api.AuditHook once the configuration is read. Calls may be made
+// concurrently.
+func auditCall(call api.Call) {
+	operation, found := operations[call.Action]
+	if !found {
+		operation = call.Action
+	}
+
+	record := audit.Record{
+		Operator:  operator(),
+		Username:  appConfig.Authentication.Username,
+		Profile:   appConfig.Profile,
+		Operation: operation,
+		TargetId:  targetId(call),
+		BodyHash:  audit.HashBody(call.Body),
+		Status:    call.Status,
+	}
+	if call.Err != nil {
+		record.Error = redact.String(call.Err.Error())
+	}
+
+	record, err := audit.Append(record)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing the audit journal '%s': %s\n", audit.Path, err)
+		slog.Error("Error writing the audit journal", "file", audit.Path, "operation", operation, "error", err)
+
+		auditErrs.Lock()
+		auditErrs.errs = append(auditErrs.errs, fmt.Errorf("%s %s: %w", operation, record.TargetId, err))
+		auditErrs.Unlock()
+		return
+	}
+
+	slog.Info("Audit record written", "seq", record.Seq, "operation", operation, "target_id", record.TargetId)
+}
+
+// auditError returns the errors writing the audit journal, nil when every
+// call was recorded
+func auditError() error {
+	auditErrs.Lock()
+	defer auditErrs.Unlock()
+
+	return errors.Join(auditErrs.errs...)
+}
+
+// operator returns the name of the user running the program
+func operator() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+
+	return os.Getenv("USER")
+}
+
+// targetId returns the id of the contract a call acted on: the new
+// contract id of a proposal, or the id in the URL of a cancel or decline
+func targetId(call api.Call) string {
+	if call.Action == api.ActionPropose {
+		var cir models.ContractInitiationResponse
+		if json.Unmarshal(call.Response, &cir) == nil {
+			return cir.ContractId()
+		}
+		return ""
+	}
+
+	u, err := url.Parse(call.URL)
+	if err != nil {
+		return ""
+	}
+
+	// The id comes before the action, as in {contracts}/{id}/cancel
+	id, err := url.PathUnescape(path.Base(path.Dir(u.EscapedPath())))
+	if err != nil {
+		return ""
+	}
+
+	return id
+}
+
+// auditCommand verifies the audit journal, or exports it as CSV to
+// csvFile or to standard output. It reports whether it succeeded.
+func auditCommand(command string, csvFile string) bool {
+	file, err := os.Open(audit.Path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening the audit journal '%s': %s\n", audit.Path, err)
+		return false
+	}
+	defer file.Close()
+
+	records, err := audit.Read(file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading the audit journal '%s': %s\n", audit.Path, err)
+		return false
+	}
+
+	switch command {
+	case "verify":
+		if err := audit.Verify(records); err != nil {
+			fmt.Fprintf(os.Stderr, "Audit journal '%s' failed verification: %s\n", audit.Path, err)
+			return false
+		}
+
+		fmt.Printf("Audit journal '%s' is intact: %d records\n", audit.Path, len(records))
+		if len(records) > 0 {
+			last := records[len(records)-1]
+			fmt.Printf("Last record: %d, hash %s\n", last.Seq, last.Hash)
+		}
+		return true
+
+	case "export":
+		if err := audit.Verify(records); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", err)
+		}
+
+		if csvFile == "" {
+			if err := audit.WriteCSV(os.Stdout, records); err != nil {
+				fmt.Fprintln(os.Stderr, "Error exporting the audit journal: ", err)
+				return false
+			}
+			return true
+		}
+
+		out, err := os.Create(csvFile)
+		if err == nil {
+			err = audit.WriteCSV(out, records)
+			err = errors.Join(err, out.Close())
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error exporting the audit journal: ", err)
+			return false
+		}
+
+		fmt.Printf("%d audit records written to '%s'\n", len(records), csvFile)
+		return true
+	}
+
+	fmt.Fprintln(os.Stderr, "Unknown audit command entered: ", command)
+	return false
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/dharm-kapadia/1source-go/api"
+)
+
+// listOptions controls how list endpoints are paged, set from the
+// --page-size and --prefetch options
+var listOptions api.PageOptions
+
+// parsePageOptions returns the paging options of the command line. paged
+// reports whether -g reads pages, as asked by --all or --limit, rather
+// than making a single GET.
+func parsePageOptions(all bool, limit string, pageSize string, prefetch string) (opts api.PageOptions, paged bool, err error) {
+	if all && limit != "" {
+		return opts, false, usageError("--all and --limit cannot be used together")
+	}
+
+	for _, option := range []struct {
+		name  string
+		value string
+		min   int
+		field *int
+	}{
+		{"--limit", limit, 1, &opts.Limit},
+		{"--page-size", pageSize, 1, &opts.Size},
+		{"--prefetch", prefetch, 0, &opts.Prefetch},
+	} {
+		if option.value == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(option.value)
+		if err != nil || n < option.min {
+			return opts, false, usageError(fmt.Sprintf("invalid %s value '%s'", option.name, option.value))
+		}
+		*option.field = n
+	}
+
+	return opts, all || limit != "", nil
+}
+
+// writeNDJSON streams the entities of a list endpoint to w as NDJSON, one
+// compact JSON entity per line, holding one entity or one page in memory
+// at a time. It returns the number of entities written.
+func writeNDJSON(w io.Writer, endPoint string, bearer string, paged bool) (int, error) {
+	out := bufio.NewWriter(w)
+	var line bytes.Buffer
+
+	write := func(entity json.RawMessage) error {
+		line.Reset()
+		if err := json.Compact(&line, entity); err != nil {
+			return err
+		}
+		line.WriteByte('\n')
+
+		_, err := out.Write(line.Bytes())
+		return err
+	}
+
+	var count int
+	var err error
+	if paged {
+		count, err = api.StreamPages(endPoint, bearer, listOptions, write)
+	} else {
+		count, err = api.Stream(endPoint, bearer, write)
+	}
+
+	return count, errors.Join(err, out.Flush())
+}
@@ -0,0 +1,179 @@
+// Package logging configures the structured, leveled logger used by
+// 1source-go. Lines written with the standard log package go through the
+// same logger at info level.
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/dharm-kapadia/1source-go/redact"
+)
+
+// Defaults used when neither the command line nor the configuration
+// file sets a value
+const (
+	DefaultLevel  = "info"
+	DefaultFormat = "text"
+	DefaultOutput = "1source-go.log"
+)
+
+// Levels and Formats are the accepted values of the level and format settings
+var (
+	Levels  = []string{"debug", "info", "warn", "error"}
+	Formats = []string{"text", "json"}
+)
+
+// Settings of the logger. Output is a file path, "stderr" or "none".
+type Settings struct {
+	Level  string
+	Format string
+	Output string
+}
+
+// Or returns the settings with the values not set taken from fallback
+func (s Settings) Or(fallback Settings) Settings {
+	if s.Level == "" {
+		s.Level = fallback.Level
+	}
+	if s.Format == "" {
+		s.Format = fallback.Format
+	}
+	if s.Output == "" {
+		s.Output = fallback.Output
+	}
+
+	return s
+}
+
+// RunId identifies one run of the program and is logged on every line
+var RunId = newId()
+
+var (
+	mu     sync.Mutex
+	output io.Closer
+)
+
+// Setup replaces the default logger with one built from the settings,
+// tagging every line with the command name and the run id. The log file
+// is only created when the first line is written to it.
+func Setup(s Settings, command string) error {
+	s = s.Or(Settings{Level: DefaultLevel, Format: DefaultFormat, Output: DefaultOutput})
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s.Level)); err != nil {
+		return fmt.Errorf("unknown log level '%s', expected one of %s", s.Level, strings.Join(Levels, ", "))
+	}
+
+	var w io.Writer
+	var closer io.Closer
+	switch s.Output {
+	case "none":
+		w = io.Discard
+	case "stderr":
+		w = os.Stderr
+	default:
+		file := &lazyFile{path: s.Output}
+		w, closer = file, file
+	}
+
+	// Credentials and sensitive fields are never written to the log
+	w = redact.Writer(w)
+
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(s.Format) {
+	case "text":
+		handler = slog.NewTextHandler(w, options)
+	case "json":
+		handler = slog.NewJSONHandler(w, options)
+	default:
+		return fmt.Errorf("unknown log format '%s', expected one of %s", s.Format, strings.Join(Formats, ", "))
+	}
+
+	slog.SetDefault(slog.New(handler).With("command", command, "run_id", RunId))
+
+	mu.Lock()
+	previous := output
+	output = closer
+	mu.Unlock()
+
+	if previous != nil {
+		return previous.Close()
+	}
+
+	return nil
+}
+
+// Close closes the log file, if any
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if output == nil {
+		return nil
+	}
+
+	err := output.Close()
+	output = nil
+
+	return err
+}
+
+// NewRequestId returns a new id for an HTTP request to the 1Source REST API
+func NewRequestId() string {
+	return newId()
+}
+
+// newId returns 8 random bytes in hex
+func newId() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "0000000000000000"
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// lazyFile is a log file opened for appending on the first write, so that
+// no file is created when nothing is logged
+type lazyFile struct {
+	path string
+	mu   sync.Mutex
+	file *os.File
+	err  error
+}
+
+func (l *lazyFile) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil && l.err == nil {
+		l.file, l.err = os.OpenFile(l.path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0644)
+	}
+	if l.err != nil {
+		return 0, l.err
+	}
+
+	return l.file.Write(p)
+}
+
+func (l *lazyFile) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+
+	err := l.file.Close()
+	l.file = nil
+
+	return err
+}
@@ -1,41 +1,32 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
 	"strings"
 
 	"github.com/Nerzal/gocloak/v13"
 	"github.com/dharm-kapadia/1source-go/api"
+	"github.com/dharm-kapadia/1source-go/audit"
+	"github.com/dharm-kapadia/1source-go/batch"
+	"github.com/dharm-kapadia/1source-go/cache"
+	"github.com/dharm-kapadia/1source-go/logging"
 	"github.com/dharm-kapadia/1source-go/models"
+	"github.com/dharm-kapadia/1source-go/snapshot"
 	"github.com/dharm-kapadia/1source-go/utils"
 )
 
 var (
-	LogFile   = "1source-go.log"
 	fileName  string
 	token     *gocloak.JWT
 	appConfig *models.AppConfig
 )
 
 func main() {
-	// Open the log file
-	var logFile, err = os.OpenFile(LogFile, os.O_APPEND|os.O_RDWR|os.O_CREATE, 0644)
-
-	if err != nil {
-		log.Panic("Error opening the Log file: ", err)
-	}
-
-	defer func(logFile *os.File) {
-		err := logFile.Close()
-		if err != nil {
-			fmt.Printf("Error closing the log file	 '%s'", LogFile)
-		}
-	}(logFile)
-
-	// Set the log to output the Log file
-	log.SetOutput(logFile)
+	var err error
 
 	// Begin parsing the command line arguments
 	if len(os.Args) == 1 {
@@ -45,7 +36,105 @@ func main() {
 		os.Exit(0)
 	}
 
-	argsWithoutProg := os.Args[1:]
+	// --dry-run may appear anywhere on the command line
+	dryRun, argsWithoutProg := utils.ExtractFlag(os.Args[1:], "--dry-run")
+	api.DryRun = dryRun
+
+	// --profile selects a named profile of the configuration file
+	profile, _, argsWithoutProg := utils.ExtractOption(argsWithoutProg, "--profile")
+
+	// Option used by the collateral command
+	fill, argsWithoutProg := utils.ExtractFlag(argsWithoutProg, "--fill")
+
+	// Options used by the batch commands
+	resume, argsWithoutProg := utils.ExtractFlag(argsWithoutProg, "--resume")
+	resultsFile, _, argsWithoutProg := utils.ExtractOption(argsWithoutProg, "--results")
+	concurrency, _, argsWithoutProg := utils.ExtractOption(argsWithoutProg, "--concurrency")
+
+	// Options used by the bulk cancel and decline commands
+	assumeYes, argsWithoutProg := utils.ExtractFlag(argsWithoutProg, "--yes")
+	var filter batch.Filter
+	filter.Party, _, argsWithoutProg = utils.ExtractOption(argsWithoutProg, "--party")
+	filter.Ticker, _, argsWithoutProg = utils.ExtractOption(argsWithoutProg, "--ticker")
+	filter.TradeDate, _, argsWithoutProg = utils.ExtractOption(argsWithoutProg, "--trade-date")
+	filter.VenueRefId, _, argsWithoutProg = utils.ExtractOption(argsWithoutProg, "--venue-ref")
+
+	// Server-side filters of the list endpoints, --party and --ticker
+	// filter -g lists too
+	var listFilter api.ListFilter
+	listFilter.Since, _, argsWithoutProg = utils.ExtractOption(argsWithoutProg, "--since")
+	listFilter.Before, _, argsWithoutProg = utils.ExtractOption(argsWithoutProg, "--before")
+	listFilter.EventType, _, argsWithoutProg = utils.ExtractOption(argsWithoutProg, "--event-type")
+	listFilter.Status, _, argsWithoutProg = utils.ExtractOption(argsWithoutProg, "--status")
+	listFilter.Isin, _, argsWithoutProg = utils.ExtractOption(argsWithoutProg, "--isin")
+	listFilter.Party, listFilter.Ticker = filter.Party, filter.Ticker
+
+	// Options used by the mark, accrue and exposure commands
+	self, _, argsWithoutProg := utils.ExtractOption(argsWithoutProg, "--self")
+	csvFile, _, argsWithoutProg := utils.ExtractOption(argsWithoutProg, "--csv")
+
+	// Options controlling the local cache
+	refresh, argsWithoutProg := utils.ExtractFlag(argsWithoutProg, "--refresh")
+	if cacheDir, found, rest := utils.ExtractOption(argsWithoutProg, "--cache-dir"); found {
+		cache.Dir = cacheDir
+		argsWithoutProg = rest
+	}
+
+	// Options of the snapshot command
+	archive, argsWithoutProg := utils.ExtractFlag(argsWithoutProg, "--archive")
+	if snapshotDir, found, rest := utils.ExtractOption(argsWithoutProg, "--snapshot-dir"); found {
+		snapshot.Dir = snapshotDir
+		argsWithoutProg = rest
+	}
+
+	// Audit journal of the calls which change state in the API
+	if auditFile, found, rest := utils.ExtractOption(argsWithoutProg, "--audit-file"); found {
+		audit.Path = auditFile
+		argsWithoutProg = rest
+	}
+
+	// Paging of the list endpoints
+	listAll, argsWithoutProg := utils.ExtractFlag(argsWithoutProg, "--all")
+	limit, _, argsWithoutProg := utils.ExtractOption(argsWithoutProg, "--limit")
+	pageSize, _, argsWithoutProg := utils.ExtractOption(argsWithoutProg, "--page-size")
+	prefetch, _, argsWithoutProg := utils.ExtractOption(argsWithoutProg, "--prefetch")
+	ndjson, argsWithoutProg := utils.ExtractFlag(argsWithoutProg, "--ndjson")
+
+	// Record every HTTP call to a cassette file, or answer them from one
+	recordFile, recording, argsWithoutProg := utils.ExtractOption(argsWithoutProg, "--record")
+	replayFile, replaying, argsWithoutProg := utils.ExtractOption(argsWithoutProg, "--replay")
+
+	// Logging options, which override the [logging] section of the
+	// configuration TOML file
+	var logSettings logging.Settings
+	logSettings.Level, _, argsWithoutProg = utils.ExtractOption(argsWithoutProg, "--log-level")
+	logSettings.Format, _, argsWithoutProg = utils.ExtractOption(argsWithoutProg, "--log-format")
+	logSettings.Output, _, argsWithoutProg = utils.ExtractOption(argsWithoutProg, "--log-output")
+
+	command := commandName(argsWithoutProg)
+	if err := logging.Setup(logSettings, command); err != nil {
+		failUsage("Error setting up logging: %s", err)
+	}
+	defer logging.Close()
+
+	var paged bool
+	listOptions, paged, err = parsePageOptions(listAll, limit, pageSize, prefetch)
+	if err != nil {
+		failUsage("%s", err)
+	}
+
+	switch {
+	case recording && replaying:
+		failUsage("--record and --replay cannot be used together")
+	case recording:
+		if err := api.Record(recordFile); err != nil {
+			fail("Error creating the cassette file", err)
+		}
+	case replaying:
+		if err := api.Replay(replayFile); err != nil {
+			fail("Error reading the cassette file", err)
+		}
+	}
 
 	// Command line of length 1 usually means help or version info requested
 	if len(argsWithoutProg) == 1 {
@@ -59,57 +148,143 @@ func main() {
 		}
 
 		// Graceful exit after displaying help
-		os.Exit(0)
+		os.Exit(exitOK)
 	}
 
-	// Command line of length 2 means either help or version
+	// Command line of length 2 means either -t TOML file or an offline command
 	if len(argsWithoutProg) == 2 {
+		// Validate a contract proposal without calling the API
+		if argsWithoutProg[0] == "validate" {
+			if !validateProposalFile(argsWithoutProg[1]) {
+				os.Exit(exitFailure)
+			}
+
+			os.Exit(exitOK)
+		}
+
+		// Compute the collateral values of a contract proposal
+		if argsWithoutProg[0] == "collateral" {
+			if !printCollateral(argsWithoutProg[1], fill) {
+				os.Exit(exitFailure)
+			}
+
+			os.Exit(exitOK)
+		}
+
+		// Validate the identifiers of a contract proposal
+		if argsWithoutProg[0] == "check-ids" {
+			if !checkProposalIds(argsWithoutProg[1]) {
+				os.Exit(exitFailure)
+			}
+
+			os.Exit(exitOK)
+		}
+
+		// Verify or export the audit journal
+		if argsWithoutProg[0] == "audit" {
+			if argsWithoutProg[1] != "verify" && argsWithoutProg[1] != "export" {
+				failUsage("Unknown audit command entered: %s", argsWithoutProg[1])
+			}
+
+			if !auditCommand(argsWithoutProg[1], csvFile) {
+				os.Exit(exitFailure)
+			}
+
+			os.Exit(exitOK)
+		}
+
+		// Serve a local 1Source simulator
+		if argsWithoutProg[0] == "simulate" {
+			if err := simulate(argsWithoutProg[1]); err != nil {
+				fail("Error running the simulator", err)
+			}
+
+			os.Exit(exitOK)
+		}
+
 		// Command line of length 2 means -t TOML file
 		if argsWithoutProg[0] == "-t" {
 			fileName = argsWithoutProg[1]
 
 			// Read and parse configuration TOML file
-			appConfig, err = utils.ReadTOML(fileName)
+			appConfig, err = utils.ReadTOML(fileName, profile)
 
 			if err != nil {
-				log.Println("Error reading and parsing configuration TOML file: ", err)
-				os.Exit(10)
+				fail("Error reading and parsing configuration TOML file", err)
 			}
+
+			configureLogging(logSettings, command)
 		} else {
-			log.Println("Unknown command line flag combination")
-			os.Exit(20)
+			failUsage("Unknown command line flag combination: %s", strings.Join(argsWithoutProg, " "))
 		}
 
 		// Graceful exit after reading and parsing configuration TOML file
-		os.Exit(0)
+		os.Exit(exitOK)
 	}
 
-	// Command line of length 3 is not supported
+	// Command line of length 3 compares two snapshots of the local store
 	if len(argsWithoutProg) == 3 {
-		log.Println("Unknown command line flag combination")
-		os.Exit(30)
+		if argsWithoutProg[0] != "diff" {
+			failUsage("Unknown command line flag combination: %s", strings.Join(argsWithoutProg, " "))
+		}
+
+		if err := diffSnapshots(argsWithoutProg[1], argsWithoutProg[2], csvFile); err != nil {
+			fail("Error comparing snapshots", err)
+		}
+
+		os.Exit(exitOK)
 	}
 
 	// Command line of length 4 contains the actual command to execute
 	if len(argsWithoutProg) == 4 {
 		fileName = argsWithoutProg[1]
 
+		// Check the configuration file, reporting every problem at once,
+		// or create it with the init wizard
+		if argsWithoutProg[2] == "config" {
+			switch argsWithoutProg[3] {
+			case "check":
+				if !checkConfig(fileName, profile) {
+					os.Exit(exitConfig)
+				}
+			case "init":
+				if err := initConfig(fileName, profile); err != nil {
+					fail("Error creating configuration", err)
+				}
+			default:
+				failUsage("Unknown config command entered: %s", argsWithoutProg[3])
+			}
+
+			os.Exit(exitOK)
+		}
+
+		// Listing the profiles of the configuration file needs neither a
+		// login nor the secrets the profiles reference
+		if argsWithoutProg[2] == "profiles" {
+			if argsWithoutProg[3] != "list" {
+				failUsage("Unknown profiles command entered: %s", argsWithoutProg[3])
+			}
+
+			if err := listProfiles(fileName, profile); err != nil {
+				fail("Error listing profiles", err)
+			}
+
+			os.Exit(exitOK)
+		}
+
 		// Read and parse configuration TOML file
-		appConfig, err = utils.ReadTOML(fileName)
+		appConfig, err = utils.ReadTOML(fileName, profile)
 
 		if err != nil {
-			log.Println("Error reading and parsing configuration TOML file: ", err)
-			os.Exit(15)
+			fail("Error reading and parsing configuration TOML file", err)
 		}
 
-		// Get Auth Token using credentials from config file
-		token, err = api.GetAuthToken(appConfig)
-		var bearer string
+		configureLogging(logSettings, command)
 
-		if err != nil {
-			log.Panic("Error retrieving Auth Token: ", err)
-		} else {
-			bearer = `Bearer ` + token.AccessToken
+		// Every call changing state in the API is recorded in the audit
+		// journal, replayed calls change nothing
+		if !replaying {
+			api.AuditHook = auditCall
 		}
 
 		// Get the 3rd and 4th command line parameters
@@ -117,161 +292,234 @@ func main() {
 		param := argsWithoutProg[2]
 		entity := argsWithoutProg[3]
 
+		// Proposals are completed and validated before any network call
+		var proposal []byte
+		if param == "-cp" {
+			var ok bool
+			if proposal, ok = loadProposal(entity); !ok {
+				os.Exit(exitFailure)
+			}
+		}
+
+		// List filters and sub-commands are checked before any network call
+		if param == "-g" {
+			if err := listFilter.Validate(entity); err != nil {
+				failUsage("%s", err)
+			}
+		}
+		if param == "snapshot" && entity != "take" {
+			failUsage("Unknown snapshot command entered: %s", entity)
+		}
+
+		// The exposure report needs no login when the local cache holds the
+		// contracts
+		offline := param == "exposure" && !refresh && cache.Exists(appConfig.Profile, "contracts")
+
+		// Get Auth Token using credentials from config file
+		var bearer string
+
+		if !offline {
+			token, err = api.GetAuthToken(appConfig)
+
+			if err != nil {
+				fail("Error retrieving Auth Token", err)
+			}
+
+			bearer = `Bearer ` + token.AccessToken
+		}
+
 		switch param {
 		// Get all of a particular type from the API
 		case "-g":
-			switch entity {
-			case "events":
-				header := "1Source Events"
-				events, err := api.GetEntity(appConfig.Endpoints.Events, bearer, header)
-				utils.PrintResults(err, events, "Error retrieving 1Source Events: ", header)
-
-			case "parties":
-				header := "1Source Parties"
-				parties, err := api.GetEntity(appConfig.Endpoints.Parties, bearer, header)
-				utils.PrintResults(err, parties, "Error retrieving 1Source Parties: ", header)
-
-			case "agreements":
-				header := "1Source Trade Agreements"
-				tas, err := api.GetEntity(appConfig.Endpoints.Agreements, bearer, header)
-				utils.PrintResults(err, tas, "Error retrieving 1Source Trade Agreements: ", header)
-
-			case "contracts":
-				header := "1Source Contracts"
-				contracts, err := api.GetEntity(appConfig.Endpoints.Contracts, bearer, header)
-				utils.PrintResults(err, contracts, "Error retrieving 1Source Contracts: ", header)
-
-			case "rerates":
-				header := "1Source Rerates"
-				rerates, err := api.GetEntity(appConfig.Endpoints.Rerates, bearer, header)
-				utils.PrintResults(err, rerates, "Error retrieving 1Source Rerates: ", header)
-
-			case "returns":
-				header := "1Source Returns"
-				returns, err := api.GetEntity(appConfig.Endpoints.Returns, bearer, header)
-				utils.PrintResults(err, returns, "Error retrieving 1Source Returns: ", header)
-
-			case "recalls":
-				header := "1Source Recalls"
-				recalls, err := api.GetEntity(appConfig.Endpoints.Recalls, bearer, header)
-				utils.PrintResults(err, recalls, "Error retrieving 1Source Recalls: ", header)
-
-			case "buyins":
-				header := "1Source Buyins"
-				buyins, err := api.GetEntity(appConfig.Endpoints.Buyins, bearer, header)
-				utils.PrintResults(err, buyins, "Error retrieving 1Source Buyins: ", header)
+			endPoint, found := appConfig.Endpoints.Endpoint(entity)
+			if !found {
+				failUsage("Unknown command-line entity entered: %s", entity)
+			}
 
-			default:
-				log.Println("Unknown command-line entity entered: ", entity)
-				fmt.Println("Unknown command-line entity entered: ", entity)
+			// Filters are applied by the API, they were checked before login
+			if endPoint, err = listFilter.URL(endPoint); err != nil {
+				fail("Error building the list URL", err)
+			}
+
+			// NDJSON is streamed entity by entity to stdout, at constant memory
+			if ndjson {
+				count, err := writeNDJSON(os.Stdout, endPoint, bearer, paged)
+				if err != nil {
+					fail("Error retrieving "+entity, err)
+				}
+
+				slog.Info("Entities written as NDJSON", "entity", entity, "count", count)
+				break
+			}
+
+			header := entityHeader(entity)
+
+			// --all and --limit read the list page by page
+			var data string
+			if paged {
+				data, err = api.ListEntity(endPoint, bearer, header, listOptions)
+			} else {
+				data, err = api.GetEntity(endPoint, bearer, header)
+			}
+			utils.PrintResults(err, data, "Error retrieving "+header+": ", header)
+			exitOnError(err)
+
+			// Keep the latest list in the local cache for offline reports,
+			// unless it was filtered or cut at --limit. A list read without
+			// --all is only its first page, and is cached as partial.
+			if listOptions.Limit == 0 && listFilter.IsEmpty() {
+				meta := cache.Meta{Profile: appConfig.Profile, URL: endPoint, Complete: listAll}
+				if err := cache.Write(entity, meta, []byte(data)); err != nil {
+					log.Printf("Error writing %s to the local cache: %s\n", entity, err)
+				}
 			}
 
 		// Get trade agreement by agreement_id
 		case "-a":
 			header := "1Source Trade Agreement"
 			prompt := fmt.Sprintf("Error retrieving Trade Agreement with agreement_id = [%s]: ", entity)
-			agreement, err := api.GetEntityById(appConfig.Endpoints.Agreements, entity, bearer, header)
+			endPoint := appConfig.Endpoints.Expand(models.AgreementPath, entity)
+			agreement, err := api.GetEntity(endPoint, bearer, header)
 			utils.PrintResults(err, agreement, prompt, header)
+			exitOnError(err)
 
 		// Get event agreement by event_id
 		case "-e":
 			header := "1Source Event"
 			prompt := fmt.Sprintf("Error retrieving Event with event_id = [%s]: ", entity)
-			event, err := api.GetEntityById(appConfig.Endpoints.Events, entity, bearer, header)
+			endPoint := appConfig.Endpoints.Expand(models.EventPath, entity)
+			event, err := api.GetEntity(endPoint, bearer, header)
 			utils.PrintResults(err, event, prompt, header)
+			exitOnError(err)
 
 		// Get contract by contract_id
 		case "-c":
 			header := "1Source Contract"
 			prompt := fmt.Sprintf("Error retrieving Contract with contract_id = [%s]: ", entity)
-			contract, err := api.GetEntityById(appConfig.Endpoints.Contracts, entity, bearer, header)
+			endPoint := appConfig.Endpoints.Expand(models.ContractPath, entity)
+			contract, err := api.GetEntity(endPoint, bearer, header)
 			utils.PrintResults(err, contract, prompt, header)
+			exitOnError(err)
 
 		// Get contract history by contract_id
 		case "-ch":
 			header := "1Source Contract History"
 			prompt := fmt.Sprintf("Error retrieving Contract History with contract_id = [%s]: ", entity)
-			endPoint := appConfig.Endpoints.Contracts + "/" + entity + "/history"
+			endPoint := appConfig.Endpoints.Expand(models.ContractHistoryPath, entity)
 			history, err := api.GetEntity(endPoint, bearer, header)
 			utils.PrintResults(err, history, prompt, header)
+			exitOnError(err)
 
 		// Get party by party_id
 		case "-p":
 			header := "1Source Party"
 			prompt := fmt.Sprintf("Error retrieving 1Source with party_id = [%s]: ", entity)
-			party, err := api.GetEntityById(appConfig.Endpoints.Parties, entity, bearer, "Party")
+			endPoint := appConfig.Endpoints.Expand(models.PartyPath, entity)
+			party, err := api.GetEntity(endPoint, bearer, "Party")
 			utils.PrintResults(err, party, prompt, header)
+			exitOnError(err)
 
 		// Propose contract
 		case "-cp":
-			// Read on JSON file specified on the command line as bytes
-			body, err := os.ReadFile(entity)
-			if err != nil {
-				fmt.Printf("Error JSON reading file [%s]: %s\n", entity, err)
-				log.Printf("Error JSON reading file [%s]: %s\n", entity, err)
-			}
-
-			// Do HTTP PostProposeContract to initiate the contract
-			resp, err := api.PostProposeContract(appConfig.Endpoints.Contracts, bearer, body)
+			// The JSON file was read, completed and validated before logging in
+			resp, err := api.PostProposeContract(appConfig.Endpoints.Contracts, bearer, proposal)
 
-			if err == nil {
+			if errors.Is(err, api.ErrDryRun) {
+				fmt.Println("Dry run: contract was not proposed")
+			} else if err == nil {
 				fmt.Println("Success: ", resp)
 			} else {
-				fmt.Println("Error proposing contract: ", err)
+				fail("Error proposing contract", err)
+			}
+
+		// Propose contracts in bulk from a directory or NDJSON file
+		case "-cpb":
+			if err := proposeBatch(entity, bearer, resultsFile, concurrency, resume); err != nil {
+				fail("Error proposing contracts", err)
+			}
+
+		// Cancel or decline PROPOSED contracts in bulk by filter
+		case "-bulk":
+			if err := bulkAction(entity, bearer, filter, concurrency, assumeYes); err != nil {
+				fail("Error in bulk "+entity, err)
+			}
+
+		// Mark open contracts to market from a price file
+		case "mark":
+			if err := markToMarket(entity, bearer, self, csvFile); err != nil {
+				fail("Error marking contracts to market", err)
+			}
+
+		// Compute rebate and fee accruals and billing statements
+		case "accrue":
+			if err := accrue(entity, bearer, self, csvFile); err != nil {
+				fail("Error computing accruals", err)
+			}
+
+		// Aggregate open contracts by counterparty, instrument, currency and venue
+		case "exposure":
+			if err := exposureReport(entity, bearer, self, csvFile, refresh); err != nil {
+				fail("Error building the exposure report", err)
+			}
+
+		// Capture every entity type into the local snapshot store
+		case "snapshot":
+			if err := takeSnapshot(bearer, archive); err != nil {
+				fail("Error taking snapshot", err)
 			}
 
 		// Cancel a proposed contract
 		case "-cc":
-			// Get the Contract by contract_id - check that it is in the proposed state
-			contract, err := api.GetEntityById(appConfig.Endpoints.Contracts, entity, bearer, "1Source Contract")
-
-			if err != nil {
-				log.Printf("Error GET %s by id [%s]: %s", "Contract", entity, err)
-			} else {
-				// Check the state of the contract
-				if strings.Contains(contract, "PROPOSED") {
-					// Do HTTP PostCancelContract to cancel the contract
-					endPoint := appConfig.Endpoints.Contracts + "/" + entity + "/cancel"
-					resp, err := api.PostCancelContract(endPoint, bearer)
-
-					if err == nil {
-						fmt.Println("Successful: ", resp)
-					} else {
-						fmt.Println("Error canceling contract: ", err)
-					}
-
-				} else {
-					fmt.Printf("Contract with id [%s] is not in PROPOSED state and cannot be canceled\n", entity)
-				}
+			if err := changeContract("cancel", entity, bearer); err != nil {
+				fail("Error canceling contract", err)
 			}
 
 		// Decline a proposed contract
 		case "-cd":
-			// Decline the Contract by contract_id - check that it is in the proposed state
-			contract, err := api.GetEntityById(appConfig.Endpoints.Contracts, entity, bearer, "1Source Contract")
-
-			if err != nil {
-				log.Printf("Error GET %s by id [%s]: %s", "Contract", entity, err)
-			} else {
-				// Check the state of the contract
-				if strings.Contains(contract, "PROPOSED") {
-					// Do HTTP PostCancelContract to cancel the contract
-					endPoint := appConfig.Endpoints.Contracts + "/" + entity + "/decline"
-					resp, err := api.PostDeclineContract(endPoint, bearer)
-
-					if err == nil {
-						fmt.Println("Successful: ", resp)
-					} else {
-						fmt.Println("Error canceling contract: ", err)
-					}
-
-				} else {
-					fmt.Printf("Contract with id [%s] is not in PROPOSED state and cannot be canceled\n", entity)
-				}
+			if err := changeContract("decline", entity, bearer); err != nil {
+				fail("Error declining contract", err)
 			}
 
 		default:
-			log.Println("Unknown command-line switch entered: ", argsWithoutProg)
+			failUsage("Unknown command-line switch entered: %s", param)
+		}
+
+		// A call which changed state but is missing from the audit journal
+		// fails the command
+		if err := auditError(); err != nil {
+			fail("Error writing the audit journal", err)
 		}
+
+		return
+	}
+
+	// Any other command line is not supported
+	failUsage("Unknown command line flag combination: %s", strings.Join(argsWithoutProg, " "))
+}
+
+// commandName returns the name of the command run, which is logged on
+// every line
+func commandName(args []string) string {
+	switch {
+	case len(args) == 4 && (args[2] == "config" || args[2] == "profiles" || args[2] == "-bulk" || args[2] == "snapshot"):
+		return args[2] + " " + args[3]
+	case len(args) == 4:
+		return args[2]
+	case len(args) > 0:
+		return args[0]
+	}
+
+	return ""
+}
+
+// configureLogging applies the [logging] section of the configuration
+// TOML file, under the logging options of the command line
+func configureLogging(flags logging.Settings, command string) {
+	settings := flags.Or(logging.Settings(appConfig.Logging))
+
+	if err := logging.Setup(settings, command); err != nil {
+		fail("Error setting up logging", err)
 	}
+
+	slog.Info("Successfully read and parsed configuration TOML file", "file", fileName, "profile", appConfig.Profile)
 }
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/dharm-kapadia/1source-go/api"
+	"github.com/dharm-kapadia/1source-go/cache"
+	"github.com/dharm-kapadia/1source-go/models"
+	"github.com/dharm-kapadia/1source-go/mtm"
+)
+
+// markToMarket revalues the open contracts at the prices in priceFile,
+// prints the collateral movements per contract and per counterparty and
+// currency, and writes the per contract movements to csvFile when given
+func markToMarket(priceFile string, bearer string, self string, csvFile string) error {
+	prices, err := mtm.LoadPrices(priceFile)
+	if err != nil {
+		return err
+	}
+
+	// Only open contracts are marked, the API filters them
+	contracts, err := fetchContracts(bearer, api.ListFilter{Status: models.ContractStatusOpen})
+	if err != nil {
+		return err
+	}
+
+	marks, skipped := mtm.MarkContracts(contracts, prices, self)
+
+	printMarks(os.Stdout, marks)
+	fmt.Println()
+	printTotals(os.Stdout, mtm.Aggregate(marks))
+
+	if len(skipped) > 0 {
+		fmt.Printf("\nSkipped %d open contract(s):\n", len(skipped))
+		for _, s := range skipped {
+			fmt.Printf("  %s: %s\n", s.ContractId, s.Reason)
+		}
+	}
+
+	log.Printf("Marked %d open contracts, skipped %d\n", len(marks), len(skipped))
+
+	if csvFile != "" {
+		file, err := os.Create(csvFile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		if err := mtm.WriteCSV(file, marks); err != nil {
+			return err
+		}
+
+		fmt.Printf("\nCollateral movements written to '%s'\n", csvFile)
+	}
+
+	return nil
+}
+
+// fetchContracts retrieves and decodes all contracts from the 1Source
+// REST API matching the server-side filter. The full list is also stored
+// in the local cache.
+func fetchContracts(bearer string, filter api.ListFilter) (models.Contracts, error) {
+	endPoint, err := filter.URL(appConfig.Endpoints.Contracts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every page is read, whatever --limit says
+	opts := api.PageOptions{Size: listOptions.Size, Prefetch: listOptions.Prefetch}
+	data, err := api.ListEntity(endPoint, bearer, "1Source Contracts", opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if filter.IsEmpty() {
+		meta := cache.Meta{Profile: appConfig.Profile, URL: endPoint, Complete: true}
+		if err := cache.Write("contracts", meta, []byte(data)); err != nil {
+			log.Println("Error writing contracts to the local cache: ", err)
+		}
+	}
+
+	var contracts models.Contracts
+	if err := json.Unmarshal([]byte(data), &contracts); err != nil {
+		return nil, fmt.Errorf("decoding contracts: %w", err)
+	}
+
+	return contracts, nil
+}
+
+// printMarks writes a table of the per contract collateral movements
+func printMarks(w io.Writer, marks []mtm.Mark) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "CONTRACT ID\tCOUNTERPARTY\tTICKER\tQUANTITY\tPRICE\tCONTRACT VALUE\tCOLLATERAL\tREQUIRED\tMOVEMENT\tCCY\t")
+
+	for _, m := range marks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%s\t\n", m.ContractId, m.Counterparty, m.Ticker,
+			m.Quantity, m.Price, m.ContractValue, m.CollateralValue, m.RequiredCollateral, m.Movement, m.Currency)
+	}
+
+	tw.Flush()
+}
+
+// printTotals writes a table of the collateral movements per counterparty
+// and currency
+func printTotals(w io.Writer, totals []mtm.Total) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "COUNTERPARTY\tCCY\tCONTRACTS\tCONTRACT VALUE\tCOLLATERAL\tREQUIRED\tMOVEMENT\t")
+
+	for _, t := range totals {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%.2f\t%.2f\t%.2f\t%.2f\t\n", t.Counterparty, t.Currency, t.Contracts,
+			t.ContractValue, t.CollateralValue, t.RequiredCollateral, t.Movement)
+	}
+
+	tw.Flush()
+}
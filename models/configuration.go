@@ -1,11 +1,21 @@
 // Models package contains the models for the application
 package models
 
+import (
+	"net/url"
+	"strings"
+)
+
 type (
 	AppConfig struct {
 		General        general
 		Endpoints      endpoints
 		Authentication authentication
+		Logging        logging
+		Redaction      redaction
+
+		// Profile is the name of the configuration profile in use
+		Profile string `toml:"-"`
 	}
 
 	general struct {
@@ -14,15 +24,30 @@ type (
 	}
 
 	endpoints struct {
-		Base       string
-		Parties    string
-		Events     string
-		Agreements string
-		Contracts  string
-		Rerates    string
-		Returns    string
-		Recalls    string
-		Buyins     string
+		Base        string
+		Api_Version string
+		Parties     string
+		Events      string
+		Agreements  string
+		Contracts   string
+		Rerates     string
+		Returns     string
+		Recalls     string
+		Buyins      string
+	}
+
+	// logging is optional, Output is a file path, "stderr" or "none"
+	logging struct {
+		Level  string
+		Format string
+		Output string
+	}
+
+	// redaction is optional, Fields are the names of sensitive fields
+	// such as account numbers, hidden from logs, dry-run output and
+	// error messages
+	redaction struct {
+		Fields []string
 	}
 
 	authentication struct {
@@ -34,3 +59,93 @@ type (
 		Client_Secret string
 	}
 )
+
+// EntityNames lists the entity types which can be listed from the 1Source
+// REST API
+var EntityNames = []string{"events", "parties", "agreements", "contracts", "rerates", "returns", "recalls", "buyins"}
+
+// Endpoint returns the endpoint listing the given entity type
+func (e endpoints) Endpoint(entity string) (string, bool) {
+	switch entity {
+	case "events":
+		return e.Events, true
+	case "parties":
+		return e.Parties, true
+	case "agreements":
+		return e.Agreements, true
+	case "contracts":
+		return e.Contracts, true
+	case "rerates":
+		return e.Rerates, true
+	case "returns":
+		return e.Returns, true
+	case "recalls":
+		return e.Recalls, true
+	case "buyins":
+		return e.Buyins, true
+	}
+
+	return "", false
+}
+
+// Path templates of the 1Source REST API sub-resources. "{<entity>}" is
+// replaced by the endpoint of the entity type and "{id}" by the id of
+// the resource.
+const (
+	AgreementPath       = "{agreements}/{id}"
+	EventPath           = "{events}/{id}"
+	PartyPath           = "{parties}/{id}"
+	ContractPath        = "{contracts}/{id}"
+	ContractHistoryPath = "{contracts}/{id}/history"
+	ContractCancelPath  = "{contracts}/{id}/cancel"
+	ContractDeclinePath = "{contracts}/{id}/decline"
+)
+
+// Derive fills in the endpoint of every entity type which is not set
+// explicitly. With an API version, endpoints are built as
+// <base>/<api_version>/ledger/<entity>. Without one, base is taken to
+// already be the ledger root, as in <host>/v1/ledger/.
+func (e *endpoints) Derive() {
+	if e.Base == "" {
+		return
+	}
+
+	root := strings.TrimRight(e.Base, "/")
+	if e.Api_Version != "" {
+		root += "/" + strings.Trim(e.Api_Version, "/") + "/ledger"
+	}
+
+	for _, field := range []struct {
+		value  *string
+		entity string
+	}{
+		{&e.Events, "events"},
+		{&e.Parties, "parties"},
+		{&e.Agreements, "agreements"},
+		{&e.Contracts, "contracts"},
+		{&e.Rerates, "rerates"},
+		{&e.Returns, "returns"},
+		{&e.Recalls, "recalls"},
+		{&e.Buyins, "buyins"},
+	} {
+		if *field.value == "" {
+			*field.value = root + "/" + field.entity
+		}
+	}
+}
+
+// Expand builds the URL of a sub-resource from one of the path templates.
+// The id is escaped for use in a URL path.
+func (e endpoints) Expand(template string, id string) string {
+	result := strings.ReplaceAll(template, "{id}", url.PathEscape(id))
+
+	for _, entity := range EntityNames {
+		placeholder := "{" + entity + "}"
+		if strings.Contains(result, placeholder) {
+			endPoint, _ := e.Endpoint(entity)
+			result = strings.ReplaceAll(result, placeholder, strings.TrimRight(endPoint, "/"))
+		}
+	}
+
+	return result
+}
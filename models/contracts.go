@@ -1,7 +1,18 @@
 // Package models contains the type structures related to 1source-go
 package models
 
+import "strings"
+
+// Contract statuses used to select contracts
+const (
+	ContractStatusProposed = "PROPOSED"
+	ContractStatusOpen     = "OPEN"
+	ContractStatusClosed   = "CLOSED"
+)
+
 type (
+	Contracts []Contract
+
 	Contract struct {
 		ContractId         string `json:"contractId"`
 		LastEventId        uint32 `json:"lastEventId"`
@@ -10,7 +21,14 @@ type (
 		LastUpdatePartyId  string `json:"lastUpdatePartyId"`
 		LastUpdateDateTime string `json:"lastUpdateDateTime"`
 		Trade              trade
-		Settlement         settlement
+		Settlement         []settlement
+	}
+
+	// ContractProposal is the body posted to the 1Source REST API to
+	// propose a contract
+	ContractProposal struct {
+		Trade      trade        `json:"trade"`
+		Settlement []settlement `json:"settlement"`
 	}
 
 	trade struct {
@@ -21,6 +39,9 @@ type (
 		BillingCurrency    string  `json:"billingCurrency"`
 		DividendRatePct    float32 `json:"dividendRatePct"`
 		TradeDate          string  `json:"tradeDate"`
+		TermType           string  `json:"termType"`
+		TermDate           string  `json:"termDate"`
+		SettlementDate     string  `json:"settlementDate"`
 		SettlementType     string  `json:"settlementType"`
 		Collateral         collateral
 		TransactingParties []transactingparties
@@ -37,7 +58,7 @@ type (
 	}
 
 	platform struct {
-		GleifLei   string `json:"gliefLei"`
+		GleifLei   string `json:"gleifLei"`
 		LegalName  string `json:"legalName"`
 		VenueName  string `json:"venueName"`
 		VenueRefId string `json:"venueRefId"`
@@ -50,10 +71,20 @@ type (
 		Sedol       string `json:"sedol"`
 		Figi        string `json:"figi"`
 		Description string `json:"description"`
+		Price       price  `json:"price"`
+	}
+
+	price struct {
+		Value    float64 `json:"value"`
+		Currency string  `json:"currency"`
+		Unit     string  `json:"unit"`
 	}
 
+	// rate holds the rebate of a cash collateralized contract, or the
+	// lending fee of a non-cash collateralized one
 	rate struct {
 		Rebate rebate
+		Fee    fixed `json:"fee"`
 	}
 
 	rebate struct {
@@ -61,18 +92,21 @@ type (
 	}
 
 	fixed struct {
-		BaseRate      float32 `json:"baseRate"`
+		BaseRate      float64 `json:"baseRate"`
 		EffectiveDate string  `json:"effectiveDate"`
-		EffectiveRate float32 `json:"effectiveRate"`
+		EffectiveRate float64 `json:"effectiveRate"`
 	}
 
 	collateral struct {
+		ContractPrice   float64 `json:"contractPrice"`
 		ContractValue   float64 `json:"contractValue"`
 		CollateralValue float64 `json:"collateralValue"`
 		Currency        string  `json:"currency"`
+		Type            string  `json:"type"`
+		DescriptionCd   string  `json:"descriptionCd"`
 		RoundingRule    uint32  `json:"roundingRule"`
 		RoundingMode    string  `json:"roundingMode"`
-		Margin          uint32  `json:"margin"`
+		Margin          float64 `json:"margin"`
 	}
 
 	transactingparties struct {
@@ -97,7 +131,7 @@ type (
 		LocalAgentBic     string `json:"localAgentBic"`
 		LocalAgentName    string `json:"localAgentName"`
 		LocalAgentAcct    string `json:"localAgentAcct"`
-		LocalMarketFields localmarketfields
+		LocalMarketFields []localmarketfields
 	}
 
 	localmarketfields struct {
@@ -105,3 +139,34 @@ type (
 		LocalFieldValue string `json:"localFieldValue"`
 	}
 )
+
+// Parties returns the partyId of the LENDER and of the BORROWER of the
+// contract
+func (c Contract) Parties() (lender string, borrower string) {
+	for _, tp := range c.Trade.TransactingParties {
+		switch tp.PartyRole {
+		case "LENDER":
+			lender = tp.Party.PartyId
+		case "BORROWER":
+			borrower = tp.Party.PartyId
+		}
+	}
+
+	return lender, borrower
+}
+
+// Counterparty returns the partyId of the transacting party on the other
+// side of the contract from self. When self is empty or not a party to
+// the contract, both sides are returned as "LENDER/BORROWER".
+func (c Contract) Counterparty(self string) string {
+	lender, borrower := c.Parties()
+
+	switch {
+	case self != "" && strings.EqualFold(self, lender):
+		return borrower
+	case self != "" && strings.EqualFold(self, borrower):
+		return lender
+	}
+
+	return lender + "/" + borrower
+}
@@ -1,11 +1,25 @@
 // Package models contains the type structures related to 1source-go
 package models
 
+import "strings"
+
 type ContractInitiationResponse struct {
-	Timestamp string `json:"timestamp"`
-	Status    uint32 `json:"status"`
-	Message   string `json:"message"`
-	Path      string `json:"path"`
+	Timestamp   string `json:"timestamp"`
+	Status      uint32 `json:"status"`
+	Message     string `json:"message"`
+	Path        string `json:"path"`
+	ResourceUri string `json:"resourceUri"`
+}
+
+// ContractId returns the id of the proposed contract, taken from the
+// last element of the resource URI returned by the 1Source REST API
+func (cir ContractInitiationResponse) ContractId() string {
+	uri := strings.TrimRight(cir.ResourceUri, "/")
+	if uri == "" {
+		return ""
+	}
+
+	return uri[strings.LastIndex(uri, "/")+1:]
 }
 
 type ContractCancelReponse struct {
@@ -0,0 +1,177 @@
+// Package mtm marks open 1Source contracts to market from a price file
+// and computes the resulting collateral movements.
+package mtm
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/dharm-kapadia/1source-go/collateral"
+	"github.com/dharm-kapadia/1source-go/models"
+)
+
+// Mark is the revaluation of one open contract. Movement is the required
+// collateral value less the current collateral value: a positive movement
+// means more collateral is due to the lender, a negative one means
+// collateral is due back to the borrower.
+type Mark struct {
+	ContractId         string
+	Counterparty       string
+	Ticker             string
+	Isin               string
+	Currency           string
+	Quantity           uint32
+	Price              float64
+	ContractValue      float64
+	CollateralValue    float64
+	RequiredCollateral float64
+	Movement           float64
+}
+
+// Skipped is an open contract which could not be marked
+type Skipped struct {
+	ContractId string
+	Reason     string
+}
+
+// Total aggregates the marks of one counterparty in one currency
+type Total struct {
+	Counterparty       string
+	Currency           string
+	Contracts          int
+	ContractValue      float64
+	CollateralValue    float64
+	RequiredCollateral float64
+	Movement           float64
+}
+
+// MarkContracts revalues every OPEN contract at the prices given, applying
+// the contract margin and rounding terms. self is the partyId used to
+// name counterparties, see models.Contract.Counterparty.
+func MarkContracts(contracts models.Contracts, prices Prices, self string) ([]Mark, []Skipped) {
+	var marks []Mark
+	var skipped []Skipped
+
+	for _, c := range contracts {
+		if c.ContractStatus != models.ContractStatusOpen {
+			continue
+		}
+
+		instrument := c.Trade.Instrument
+		price, ok := prices.Lookup(instrument.Ticker, instrument.Isin)
+		if !ok {
+			skipped = append(skipped, Skipped{ContractId: c.ContractId, Reason: "no price for " + instrumentName(instrument.Ticker, instrument.Isin)})
+			continue
+		}
+
+		values, err := collateral.Compute(collateral.ContractTerms(c, price))
+		if err != nil {
+			skipped = append(skipped, Skipped{ContractId: c.ContractId, Reason: err.Error()})
+			continue
+		}
+
+		current := c.Trade.Collateral.CollateralValue
+
+		marks = append(marks, Mark{
+			ContractId:         c.ContractId,
+			Counterparty:       c.Counterparty(self),
+			Ticker:             instrument.Ticker,
+			Isin:               instrument.Isin,
+			Currency:           c.Trade.Collateral.Currency,
+			Quantity:           c.Trade.Quantity,
+			Price:              price,
+			ContractValue:      values.ContractValue,
+			CollateralValue:    current,
+			RequiredCollateral: values.CollateralValue,
+			Movement:           collateral.RoundCents(values.CollateralValue - current),
+		})
+	}
+
+	return marks, skipped
+}
+
+// Aggregate totals the marks per counterparty and currency, sorted by
+// counterparty then currency
+func Aggregate(marks []Mark) []Total {
+	type key struct{ counterparty, currency string }
+	totals := make(map[key]*Total)
+
+	for _, m := range marks {
+		k := key{m.Counterparty, m.Currency}
+		t, ok := totals[k]
+		if !ok {
+			t = &Total{Counterparty: m.Counterparty, Currency: m.Currency}
+			totals[k] = t
+		}
+
+		t.Contracts++
+		t.ContractValue = collateral.RoundCents(t.ContractValue + m.ContractValue)
+		t.CollateralValue = collateral.RoundCents(t.CollateralValue + m.CollateralValue)
+		t.RequiredCollateral = collateral.RoundCents(t.RequiredCollateral + m.RequiredCollateral)
+		t.Movement = collateral.RoundCents(t.Movement + m.Movement)
+	}
+
+	result := make([]Total, 0, len(totals))
+	for _, t := range totals {
+		result = append(result, *t)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Counterparty != result[j].Counterparty {
+			return result[i].Counterparty < result[j].Counterparty
+		}
+		return result[i].Currency < result[j].Currency
+	})
+
+	return result
+}
+
+// WriteCSV writes one CSV record per mark, preceded by a header
+func WriteCSV(w io.Writer, marks []Mark) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{"contractId", "counterparty", "ticker", "isin", "currency", "quantity", "price",
+		"contractValue", "collateralValue", "requiredCollateral", "movement"}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, m := range marks {
+		record := []string{
+			m.ContractId,
+			m.Counterparty,
+			m.Ticker,
+			m.Isin,
+			m.Currency,
+			strconv.FormatUint(uint64(m.Quantity), 10),
+			formatAmount(m.Price),
+			formatAmount(m.ContractValue),
+			formatAmount(m.CollateralValue),
+			formatAmount(m.RequiredCollateral),
+			formatAmount(m.Movement),
+		}
+
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// instrumentName names an instrument in messages
+func instrumentName(ticker string, isin string) string {
+	if ticker != "" && isin != "" {
+		return ticker + "/" + isin
+	}
+
+	return ticker + isin
+}
+
+// formatAmount formats a monetary amount with two decimals
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
@@ -0,0 +1,122 @@
+package mtm
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/dharm-kapadia/1source-go/models"
+)
+
+// contract returns the JSON of a contract lent by TLEN-US to borrower
+func contract(id string, status string, borrower string, instrument string, quantity string, collateral string) string {
+	return `{"contractId":"` + id + `","contractStatus":"` + status + `","trade":{
+		"instrument":` + instrument + `,"quantity":` + quantity + `,"collateral":` + collateral + `,
+		"transactingParties":[
+			{"partyRole":"LENDER","party":{"partyId":"TLEN-US"}},
+			{"partyRole":"BORROWER","party":{"partyId":"` + borrower + `"}}]}}`
+}
+
+// markedContracts decodes the test contracts
+func markedContracts(t *testing.T) models.Contracts {
+	t.Helper()
+
+	versions := "[" +
+		contract("c1", "OPEN", "TBORR-US", `{"ticker":"IBM"}`, "1000",
+			`{"currency":"USD","collateralValue":150000,"margin":102}`) + "," +
+		contract("c2", "OPEN", "TBORR-US", `{"ticker":"MSFT","isin":"US5949181045"}`, "500",
+			`{"currency":"USD","collateralValue":160000,"margin":105,"roundingRule":1000,"roundingMode":"ALWAYSUP"}`) + "," +
+		contract("c3", "OPEN", "OTHER-BORR", `{"ticker":"AAPL"}`, "200",
+			`{"currency":"USD","collateralValue":36100,"margin":100,"roundingRule":100,"roundingMode":"ALWAYSDOWN"}`) + "," +
+		contract("c4", "OPEN", "TBORR-US", `{"ticker":"SAP"}`, "100",
+			`{"currency":"EUR","collateralValue":12000,"margin":102}`) + "," +
+		contract("c5", "OPEN", "TBORR-US", `{"ticker":"XYZ"}`, "100",
+			`{"currency":"USD","collateralValue":1000,"margin":102}`) + "," +
+		contract("c6", "PROPOSED", "TBORR-US", `{"ticker":"IBM"}`, "100",
+			`{"currency":"USD","collateralValue":1000,"margin":102}`) + "," +
+		contract("c7", "OPEN", "TBORR-US", `{"ticker":"IBM"}`, "100",
+			`{"currency":"USD","collateralValue":1000}`) + "]"
+
+	var contracts models.Contracts
+	if err := json.Unmarshal([]byte(versions), &contracts); err != nil {
+		t.Fatal(err)
+	}
+	return contracts
+}
+
+// prices of the test contracts, MSFT by its ISIN
+var prices = Prices{"IBM": 150.25, "US5949181045": 300, "MSFT": 1, "AAPL": 180.10, "SAP": 120.555}
+
+func TestMarkContracts(t *testing.T) {
+	marks, skipped := MarkContracts(markedContracts(t), prices, "TLEN-US")
+
+	expected := []Mark{
+		// 1000 × 150.25 = 150250.00, at 102% 153255.00 is required
+		// against 150000.00 held: a margin call of 3255.00
+		{ContractId: "c1", Counterparty: "TBORR-US", Ticker: "IBM", Currency: "USD", Quantity: 1000, Price: 150.25,
+			ContractValue: 150250, CollateralValue: 150000, RequiredCollateral: 153255, Movement: 3255},
+
+		// 500 × 300 = 150000.00, at 105% 157500.00 rounded up to 158000
+		// against 160000.00 held: 2000.00 is returned
+		{ContractId: "c2", Counterparty: "TBORR-US", Ticker: "MSFT", Isin: "US5949181045", Currency: "USD", Quantity: 500, Price: 300,
+			ContractValue: 150000, CollateralValue: 160000, RequiredCollateral: 158000, Movement: -2000},
+
+		// 200 × 180.10 = 36020.00, at 100% rounded down to 36000 against
+		// 36100.00 held: 100.00 is returned
+		{ContractId: "c3", Counterparty: "OTHER-BORR", Ticker: "AAPL", Currency: "USD", Quantity: 200, Price: 180.10,
+			ContractValue: 36020, CollateralValue: 36100, RequiredCollateral: 36000, Movement: -100},
+
+		// 100 × 120.555 = 12055.50, at 102% 12296.61 against 12000.00
+		// held: a margin call of 296.61
+		{ContractId: "c4", Counterparty: "TBORR-US", Ticker: "SAP", Currency: "EUR", Quantity: 100, Price: 120.555,
+			ContractValue: 12055.5, CollateralValue: 12000, RequiredCollateral: 12296.61, Movement: 296.61},
+	}
+	if !reflect.DeepEqual(marks, expected) {
+		t.Errorf("MarkContracts() = %+v, expected %+v", marks, expected)
+	}
+
+	// Contracts which are not OPEN are ignored, others which cannot be
+	// marked are reported
+	expectedSkipped := []Skipped{
+		{ContractId: "c5", Reason: "no price for XYZ"},
+		{ContractId: "c7", Reason: "margin must be greater than zero"},
+	}
+	if !reflect.DeepEqual(skipped, expectedSkipped) {
+		t.Errorf("MarkContracts() skipped %+v, expected %+v", skipped, expectedSkipped)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	marks, _ := MarkContracts(markedContracts(t), prices, "TLEN-US")
+
+	expected := []Total{
+		{Counterparty: "OTHER-BORR", Currency: "USD", Contracts: 1,
+			ContractValue: 36020, CollateralValue: 36100, RequiredCollateral: 36000, Movement: -100},
+		{Counterparty: "TBORR-US", Currency: "EUR", Contracts: 1,
+			ContractValue: 12055.5, CollateralValue: 12000, RequiredCollateral: 12296.61, Movement: 296.61},
+
+		// The call on c1 and the return on c2 net to 1255.00
+		{Counterparty: "TBORR-US", Currency: "USD", Contracts: 2,
+			ContractValue: 300250, CollateralValue: 310000, RequiredCollateral: 311255, Movement: 1255},
+	}
+	if totals := Aggregate(marks); !reflect.DeepEqual(totals, expected) {
+		t.Errorf("Aggregate() = %+v, expected %+v", totals, expected)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	marks, _ := MarkContracts(markedContracts(t), prices, "TLEN-US")
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, marks[:2]); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "contractId,counterparty,ticker,isin,currency,quantity,price,contractValue,collateralValue,requiredCollateral,movement\n" +
+		"c1,TBORR-US,IBM,,USD,1000,150.25,150250.00,150000.00,153255.00,3255.00\n" +
+		"c2,TBORR-US,MSFT,US5949181045,USD,500,300.00,150000.00,160000.00,158000.00,-2000.00\n"
+	if buf.String() != expected {
+		t.Errorf("WriteCSV() = %q, expected %q", buf.String(), expected)
+	}
+}
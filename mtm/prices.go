@@ -0,0 +1,86 @@
+// Package mtm marks open 1Source contracts to market from a price file
+// and computes the resulting collateral movements.
+package mtm
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Prices holds close prices keyed by upper-case ticker or ISIN
+type Prices map[string]float64
+
+// LoadPrices reads a CSV price file with one "ticker_or_isin,price" record
+// per line. Blank lines, lines starting with '#' and a header, a first
+// record whose price column is not a number, are skipped.
+func LoadPrices(path string) (Prices, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	prices := make(Prices)
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Line of the record in the file, counting comments and blank lines
+		line, _ := reader.FieldPos(0)
+
+		if len(record) < 2 {
+			return nil, fmt.Errorf("%s:%d: expected ticker_or_isin,price", path, line)
+		}
+
+		key := strings.ToUpper(strings.TrimSpace(record[0]))
+		price, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if err != nil {
+			if first {
+				// Header record
+				continue
+			}
+			return nil, fmt.Errorf("%s:%d: invalid price '%s'", path, line, record[1])
+		}
+
+		if price <= 0 {
+			return nil, fmt.Errorf("%s:%d: price must be greater than zero", path, line)
+		}
+
+		prices[key] = price
+	}
+
+	return prices, nil
+}
+
+// Lookup returns the price of an instrument, preferring the ISIN over the
+// ticker
+func (p Prices) Lookup(ticker string, isin string) (float64, bool) {
+	if isin != "" {
+		if price, ok := p[strings.ToUpper(isin)]; ok {
+			return price, true
+		}
+	}
+
+	if ticker != "" {
+		if price, ok := p[strings.ToUpper(ticker)]; ok {
+			return price, true
+		}
+	}
+
+	return 0, false
+}
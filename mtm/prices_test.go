@@ -0,0 +1,58 @@
+package mtm
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writePrices writes a price file and returns its path
+func writePrices(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "prices.csv")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrices(t *testing.T) {
+	path := writePrices(t, "# close prices\n\nticker,price\nibm, 150.25\nUS4592001014,150.5\n")
+
+	prices, err := LoadPrices(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Prices{"IBM": 150.25, "US4592001014": 150.5}
+	if !reflect.DeepEqual(prices, expected) {
+		t.Errorf("LoadPrices() = %v, expected %v", prices, expected)
+	}
+	if price, ok := prices.Lookup("IBM", "US4592001014"); !ok || price != 150.5 {
+		t.Errorf("Lookup() = %v, %v, expected the ISIN price", price, ok)
+	}
+}
+
+func TestLoadPricesErrorLine(t *testing.T) {
+	tests := []struct {
+		content string
+		line    string
+	}{
+		// Comments and blank lines count in the line numbers
+		{"# close prices\n\nIBM,150\n\n# more\nJPM,abc\n", ":6: invalid price"},
+		{"IBM,150\nJPM,-1\n", ":2: price must be greater than zero"},
+		{"IBM,150\n#\nJPM\n", ":3: expected ticker_or_isin,price"},
+		// Only the first record can be a header
+		{"IBM,150\nticker,price\n", ":2: invalid price"},
+	}
+
+	for _, tt := range tests {
+		_, err := LoadPrices(writePrices(t, tt.content))
+		if err == nil || !strings.Contains(err.Error(), tt.line) {
+			t.Errorf("LoadPrices(%q) error = %v, expected %q", tt.content, err, tt.line)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/dharm-kapadia/1source-go/utils"
+)
+
+// listProfiles prints the profiles of a configuration file, marking the
+// active one. Secret references are not resolved.
+func listProfiles(filename string, active string) error {
+	infos, err := utils.ListProfiles(filename)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\tPROFILE\tINHERITS\tAUTH URL\tUSERNAME")
+
+	if active == "" {
+		active = utils.DefaultProfile
+	}
+
+	for _, info := range infos {
+		mark := ""
+		if info.Name == active {
+			mark = "*"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", mark, info.Name, info.Inherits, info.AuthURL, info.Username)
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dharm-kapadia/1source-go/api"
+	"github.com/dharm-kapadia/1source-go/batch"
+	"github.com/dharm-kapadia/1source-go/collateral"
+	"github.com/dharm-kapadia/1source-go/models"
+	"github.com/dharm-kapadia/1source-go/validation"
+)
+
+const (
+	defaultResultsFile = "propose-results.ndjson"
+	defaultConcurrency = 4
+)
+
+// proposeBatch proposes every contract found at path over one session,
+// recording each outcome in the results file. Missing collateral values
+// are filled in, and proposals failing offline validation are recorded
+// as failed without calling the API. With resume set, inputs
+// already recorded as succeeded are skipped.
+func proposeBatch(path string, bearer string, resultsFile string, concurrency string, resume bool) error {
+	if resultsFile == "" {
+		resultsFile = defaultResultsFile
+	}
+
+	workers := defaultConcurrency
+	if concurrency != "" {
+		n, err := strconv.Atoi(concurrency)
+		if err != nil || n < 1 {
+			return usageError(fmt.Sprintf("invalid --concurrency value '%s'", concurrency))
+		}
+		workers = n
+	}
+
+	inputs, err := batch.LoadInputs(path)
+	if err != nil {
+		return err
+	}
+
+	var summary batch.Summary
+	pending := inputs
+
+	if resume {
+		succeeded, err := batch.ReadResults(resultsFile)
+		if err != nil {
+			return err
+		}
+
+		pending = batch.Pending(inputs, succeeded)
+		summary.Skipped = len(inputs) - len(pending)
+	}
+
+	// A dry run sends nothing, so the results file is left untouched for
+	// a later --resume
+	var writer *batch.ResultWriter
+	if !api.DryRun {
+		if writer, err = batch.NewResultWriter(resultsFile, resume); err != nil {
+			return err
+		}
+		defer writer.Close()
+	}
+
+	log.Printf("Proposing %d contracts from '%s' with %d workers\n", len(pending), path, workers)
+
+	var writeErr error
+	propose := func(body []byte) (string, error) {
+		body = fillProposal(body)
+
+		if problems := validation.ValidateProposal(body); len(problems) > 0 {
+			return "", fmt.Errorf("invalid proposal: %w", problems)
+		}
+
+		cir, err := api.ProposeContract(appConfig.Endpoints.Contracts, bearer, body)
+		if err != nil {
+			return "", err
+		}
+
+		return cir.ContractId(), nil
+	}
+
+	run := batch.ProposeAll(pending, workers, propose, func(result batch.Result) {
+		switch {
+		case result.DryRun:
+			fmt.Printf("%s: dry run, contract was not proposed\n", result.Input)
+		case result.Succeeded():
+			fmt.Printf("%s: proposed contract %s\n", result.Input, result.ContractId)
+		default:
+			fmt.Printf("%s: %s\n", result.Input, strings.TrimSpace(result.Error))
+		}
+
+		if writer == nil {
+			return
+		}
+		if err := writer.Write(result); err != nil && writeErr == nil {
+			writeErr = err
+		}
+	})
+
+	summary.Succeeded, summary.Failed, summary.DryRun = run.Succeeded, run.Failed, run.DryRun
+
+	if api.DryRun {
+		fmt.Printf("\nDry run: %d, Failed: %d, Skipped: %d (results file '%s' unchanged)\n",
+			summary.DryRun, summary.Failed, summary.Skipped, resultsFile)
+	} else {
+		fmt.Printf("\nProposed: %d, Failed: %d, Skipped: %d (results in '%s')\n",
+			summary.Succeeded, summary.Failed, summary.Skipped, resultsFile)
+	}
+	log.Printf("Batch propose finished. Proposed: %d, Failed: %d, Skipped: %d, Dry run: %d\n",
+		summary.Succeeded, summary.Failed, summary.Skipped, summary.DryRun)
+
+	if writeErr != nil {
+		return writeErr
+	}
+
+	if summary.Failed > 0 {
+		return fmt.Errorf("%d of %d contracts could not be proposed", summary.Failed, len(pending))
+	}
+
+	return nil
+}
+
+// loadProposal reads a contract proposal file, fills in missing
+// collateral values and validates it offline. It reports whether the
+// proposal may be submitted.
+func loadProposal(path string) ([]byte, bool) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error JSON reading file [%s]: %s\n", path, err)
+		log.Printf("Error JSON reading file [%s]: %s\n", path, err)
+		return nil, false
+	}
+
+	body = fillProposal(body)
+
+	if problems := validation.ValidateProposal(body); len(problems) > 0 {
+		printProblems(path, problems)
+		return nil, false
+	}
+
+	return body, true
+}
+
+// fillProposal fills in missing collateral values of a proposal. A
+// proposal whose values cannot be derived is returned unchanged, so
+// that validation reports what is missing.
+func fillProposal(body []byte) []byte {
+	if !json.Valid(body) {
+		return body
+	}
+
+	filled, _, err := collateral.FillProposal(body)
+	if err != nil {
+		return body
+	}
+
+	return filled
+}
+
+// validateProposalFile checks a contract proposal file offline and
+// prints any problems found. It reports whether the proposal is valid.
+func validateProposalFile(path string) bool {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error JSON reading file [%s]: %s\n", path, err)
+		log.Printf("Error JSON reading file [%s]: %s\n", path, err)
+		return false
+	}
+
+	problems := validation.ValidateProposal(body)
+	if len(problems) == 0 {
+		fmt.Printf("Contract proposal [%s] is valid\n", path)
+		return true
+	}
+
+	printProblems(path, problems)
+
+	return false
+}
+
+// printProblems prints the problems found in a contract proposal
+func printProblems(path string, problems validation.Problems) {
+	fmt.Printf("Contract proposal [%s] has %d problem(s):\n", path, len(problems))
+	for _, p := range problems {
+		fmt.Println("  ", p.Error())
+	}
+	log.Printf("Contract proposal [%s] failed validation: %s\n", path, problems)
+}
+
+// checkProposalIds validates the identifiers of a contract proposal file
+// and prints the result of each check. It reports whether all are valid.
+func checkProposalIds(path string) bool {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error JSON reading file [%s]: %s\n", path, err)
+		log.Printf("Error JSON reading file [%s]: %s\n", path, err)
+		return false
+	}
+
+	var proposal models.ContractProposal
+	if err := json.Unmarshal(body, &proposal); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing JSON file [%s]: %s\n", path, err)
+		return false
+	}
+
+	valid := true
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FIELD\tKIND\tVALUE\tRESULT")
+
+	for _, check := range validation.CheckIdentifiers(&proposal) {
+		result := "OK"
+		if check.Err != nil {
+			result = check.Err.Error()
+			valid = false
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", check.Field, check.Kind, check.Value, result)
+	}
+
+	tw.Flush()
+
+	return valid
+}
+
+// printCollateral prints the collateral values of a contract proposal
+// next to the values derived from its terms. With fill set, the proposal
+// with missing values filled in is printed instead.
+func printCollateral(path string, fill bool) bool {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error JSON reading file [%s]: %s\n", path, err)
+		log.Printf("Error JSON reading file [%s]: %s\n", path, err)
+		return false
+	}
+
+	if fill {
+		filled, _, err := collateral.FillProposal(body)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error filling in collateral values of [%s]: %s\n", path, err)
+			return false
+		}
+
+		fmt.Println(string(filled))
+		return true
+	}
+
+	var proposal models.ContractProposal
+	if err := json.Unmarshal(body, &proposal); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing JSON file [%s]: %s\n", path, err)
+		return false
+	}
+
+	terms := collateral.ProposalTerms(&proposal)
+	values, err := collateral.Compute(terms)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error computing collateral values of [%s]: %s\n", path, err)
+		return false
+	}
+
+	block := proposal.Trade.Collateral
+	matches := func(a, b float64) string {
+		if collateral.Equal(a, b) {
+			return "OK"
+		}
+		return "MISMATCH"
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FIELD\tPROPOSAL\tCOMPUTED\tRESULT")
+	fmt.Fprintf(tw, "contractPrice\t%.2f\t%.2f\t%s\n", block.ContractPrice, terms.Price, matches(block.ContractPrice, terms.Price))
+	fmt.Fprintf(tw, "contractValue\t%.2f\t%.2f\t%s\n", block.ContractValue, values.ContractValue, matches(block.ContractValue, values.ContractValue))
+	fmt.Fprintf(tw, "collateralValue\t%.2f\t%.2f\t%s\n", block.CollateralValue, values.CollateralValue, matches(block.CollateralValue, values.CollateralValue))
+	tw.Flush()
+
+	return collateral.Equal(block.ContractValue, values.ContractValue) &&
+		collateral.Equal(block.CollateralValue, values.CollateralValue)
+}
@@ -0,0 +1,210 @@
+// Package redact hides tokens, credentials and other sensitive values
+// before they are written to logs, dry-run output or error messages.
+//
+// Text is redacted in three ways:
+//   - the credentials of Bearer and Basic authorization values
+//   - the values of sensitive fields, in JSON ("password": "...") and in
+//     key=value form (password=...)
+//   - secret values registered with AddSecrets, wherever they appear
+package redact
+
+import (
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces every sensitive value
+const Redacted = "<redacted>"
+
+// MinSecretLength is the length under which a registered secret is not
+// searched for, as it would redact unrelated text
+const MinSecretLength = 4
+
+// DefaultFields are the fields always treated as sensitive. Field names
+// are matched without regard to case.
+var DefaultFields = []string{
+	"authorization",
+	"password",
+	"client_secret", "clientSecret",
+	"access_token", "accessToken",
+	"refresh_token", "refreshToken",
+	"id_token", "idToken",
+	"localAgentAcct",
+}
+
+var (
+	mu      sync.RWMutex
+	fields  = append([]string(nil), DefaultFields...)
+	secrets []string
+
+	// Rebuilt whenever fields change
+	jsonField        *regexp.Regexp
+	escapedJSONField *regexp.Regexp
+	formField        *regexp.Regexp
+)
+
+// authorization matches the credentials of an authorization value
+var authorization = regexp.MustCompile(`(?i)\b(Bearer|Basic)\s+[A-Za-z0-9\-._~+/]+=*`)
+
+func init() {
+	compile()
+}
+
+// AddFields adds sensitive field names, such as account numbers
+func AddFields(names ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name != "" && !contains(fields, name) {
+			fields = append(fields, name)
+		}
+	}
+
+	compile()
+}
+
+// Fields returns the sensitive field names
+func Fields() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return append([]string(nil), fields...)
+}
+
+// AddSecrets registers secret values, such as a password read from the
+// configuration or a token received at login, which are then redacted
+// wherever they appear. Values shorter than MinSecretLength are ignored.
+func AddSecrets(values ...string) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, value := range values {
+		if len(value) >= MinSecretLength && !slices.Contains(secrets, value) {
+			secrets = append(secrets, value)
+		}
+	}
+
+	// Longest first, so a secret containing another is hidden whole
+	sort.Slice(secrets, func(i, j int) bool {
+		return len(secrets[i]) > len(secrets[j])
+	})
+}
+
+// Reset forgets the registered secrets and the added fields
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	fields = append([]string(nil), DefaultFields...)
+	secrets = nil
+	compile()
+}
+
+// compile builds the field expressions, mu must be held
+func compile() {
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = regexp.QuoteMeta(f)
+	}
+	alternation := strings.Join(names, "|")
+
+	jsonField = regexp.MustCompile(`(?i)("(?:` + alternation + `)"\s*:\s*)("(?:[^"\\]|\\.)*"|[^\s,}\]]+)`)
+	// JSON quoted inside a string, as in a logged body
+	escapedJSONField = regexp.MustCompile(`(?i)(\\"(?:` + alternation + `)\\"\s*:\s*)(\\"[^"]*?\\"|[^\s,}\]\\"]+)`)
+	formField = regexp.MustCompile(`(?i)\b((?:` + alternation + `)=)("(?:[^"\\]|\\.)*"|[^\s&"]+)`)
+}
+
+// String returns s with every sensitive value replaced by Redacted
+func String(s string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, secret := range secrets {
+		s = strings.ReplaceAll(s, secret, Redacted)
+	}
+
+	s = authorization.ReplaceAllString(s, "$1 "+Redacted)
+	s = jsonField.ReplaceAllString(s, `$1"`+Redacted+`"`)
+	s = escapedJSONField.ReplaceAllString(s, `$1\"`+Redacted+`\"`)
+	s = formField.ReplaceAllString(s, "$1"+Redacted)
+
+	return s
+}
+
+// Bytes returns b with every sensitive value replaced by Redacted
+func Bytes(b []byte) []byte {
+	return []byte(String(string(b)))
+}
+
+// Header returns the value of an HTTP header safe to print. The
+// Authorization header keeps its scheme only.
+func Header(name string, value string) string {
+	switch http.CanonicalHeaderKey(name) {
+	case "Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie":
+		scheme, _, found := strings.Cut(value, " ")
+		if !found {
+			return Redacted
+		}
+		return scheme + " " + Redacted
+	}
+
+	return String(value)
+}
+
+// Err returns an error whose message is redacted. errors.Is and
+// errors.As still see the original error.
+func Err(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return redactedError{err}
+}
+
+type redactedError struct {
+	err error
+}
+
+func (e redactedError) Error() string {
+	return String(e.err.Error())
+}
+
+func (e redactedError) Unwrap() error {
+	return e.err
+}
+
+// Writer returns a writer redacting every write before passing it to w.
+// Each write must hold whole values, as log lines do.
+func Writer(w io.Writer) io.Writer {
+	return writer{w}
+}
+
+type writer struct {
+	w io.Writer
+}
+
+func (rw writer) Write(p []byte) (int, error) {
+	if _, err := rw.w.Write(Bytes(p)); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// contains reports whether list holds value, without regard to case
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/dharm-kapadia/1source-go/simulator"
+)
+
+// simulate serves a local 1Source simulator on addr, such as :8080,
+// until the program is stopped. It prints a configuration to use it.
+func simulate(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	url := "http://" + listenerHost(listener.Addr().String())
+
+	fmt.Printf("1Source simulator listening on %s\n\n", url)
+	fmt.Println("Configuration TOML file sections:")
+	fmt.Printf("[general]\nauth_url = '%s/auth'\nrealm_name = '%s'\n\n", url, simulator.Realm)
+	fmt.Printf("[endpoints]\nbase = '%s%s'\n\n", url, strings.TrimRight(simulator.LedgerPath, "/"))
+	fmt.Println("Users, with password 'password':")
+	for _, u := range simulator.DefaultUsers {
+		fmt.Printf("  %s\tparty %s\n", u.Username, u.PartyId)
+	}
+
+	slog.Info("Simulator listening", "url", url)
+	return http.Serve(listener, simulator.New())
+}
+
+// listenerHost returns the address of a listener usable in a URL,
+// naming localhost when listening on every interface
+func listenerHost(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "localhost"
+	}
+
+	return net.JoinHostPort(host, port)
+}
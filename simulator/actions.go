@@ -0,0 +1,315 @@
+// Package simulator is a stateful, local stand-in for the 1Source REST API
+// and its KeyCloak login, for development and tests without network.
+package simulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dharm-kapadia/1source-go/models"
+)
+
+// Event types generated by the lifecycle of rerates, returns, recalls
+// and buyins
+const (
+	EventRerateProposed     = "RERATE_PROPOSED"
+	EventRerateApplied      = "RERATE_APPLIED"
+	EventRerateDeclined     = "RERATE_DECLINED"
+	EventRerateCanceled     = "RERATE_CANCELED"
+	EventReturnPending      = "RETURN_PENDING"
+	EventReturnAcknowledged = "RETURN_ACKNOWLEDGED"
+	EventReturnCanceled     = "RETURN_CANCELED"
+	EventRecallOpened       = "RECALL_OPENED"
+	EventRecallAcknowledged = "RECALL_ACKNOWLEDGED"
+	EventRecallCanceled     = "RECALL_CANCELED"
+	EventBuyinProposed      = "BUYIN_PROPOSED"
+	EventBuyinAccepted      = "BUYIN_ACCEPTED"
+	EventBuyinCanceled      = "BUYIN_CANCELED"
+)
+
+// Statuses of rerates, returns, recalls and buyins
+const (
+	StatusProposed     = "PROPOSED"
+	StatusApplied      = "APPLIED"
+	StatusDeclined     = "DECLINED"
+	StatusCanceled     = "CANCELED"
+	StatusPending      = "PENDING"
+	StatusAcknowledged = "ACKNOWLEDGED"
+	StatusOpen         = "OPEN"
+	StatusAccepted     = "ACCEPTED"
+)
+
+// Party roles which can start an action, any role when empty
+const (
+	roleLender   = "LENDER"
+	roleBorrower = "BORROWER"
+)
+
+// actionKind describes the lifecycle of one type of action on an open
+// contract: who starts it, its first status and event, and the
+// transitions allowed from that first status
+type actionKind struct {
+	entity      string
+	idField     string
+	statusField string
+	initiator   string
+	status      string
+	event       string
+	transitions map[string]transition
+}
+
+// transition is an action moving an action out of its first status,
+// made by its initiator or by the counterparty
+type transition struct {
+	byInitiator bool
+	status      string
+	event       string
+}
+
+// actionKinds holds the lifecycles of the entity types acting on an open
+// contract, by entity type
+var actionKinds = map[string]*actionKind{
+	"rerates": {
+		entity: "rerates", idField: "rerateId", statusField: "rerateStatus",
+		status: StatusProposed, event: EventRerateProposed,
+		transitions: map[string]transition{
+			"approve": {status: StatusApplied, event: EventRerateApplied},
+			"decline": {status: StatusDeclined, event: EventRerateDeclined},
+			"cancel":  {byInitiator: true, status: StatusCanceled, event: EventRerateCanceled},
+		},
+	},
+	"returns": {
+		entity: "returns", idField: "returnId", statusField: "returnStatus", initiator: roleBorrower,
+		status: StatusPending, event: EventReturnPending,
+		transitions: map[string]transition{
+			"acknowledge": {status: StatusAcknowledged, event: EventReturnAcknowledged},
+			"cancel":      {byInitiator: true, status: StatusCanceled, event: EventReturnCanceled},
+		},
+	},
+	"recalls": {
+		entity: "recalls", idField: "recallId", statusField: "recallStatus", initiator: roleLender,
+		status: StatusOpen, event: EventRecallOpened,
+		transitions: map[string]transition{
+			"acknowledge": {status: StatusAcknowledged, event: EventRecallAcknowledged},
+			"cancel":      {byInitiator: true, status: StatusCanceled, event: EventRecallCanceled},
+		},
+	},
+	"buyins": {
+		entity: "buyins", idField: "buyinId", statusField: "buyinStatus", initiator: roleLender,
+		status: StatusProposed, event: EventBuyinProposed,
+		transitions: map[string]transition{
+			"accept": {status: StatusAccepted, event: EventBuyinAccepted},
+			"cancel": {byInitiator: true, status: StatusCanceled, event: EventBuyinCanceled},
+		},
+	},
+}
+
+// action is the state of one rerate, return, recall or buyin
+type action struct {
+	kind        *actionKind
+	id          string
+	contractId  string
+	status      string
+	initiator   string
+	lender      string
+	borrower    string
+	ticker      string
+	isin        string
+	created     uint64
+	lastEventId uint64
+	lastParty   string
+	lastUpdate  time.Time
+	fields      map[string]any
+}
+
+// Rerate proposes a new rate for an OPEN contract, which the counterparty
+// approves or declines and the proposer can cancel
+func (s *Simulator) Rerate(party string, contractId string, body []byte) (string, error) {
+	return s.startAction(actionKinds["rerates"], party, contractId, body)
+}
+
+// Return starts the return of shares of an OPEN contract by its borrower,
+// which the lender acknowledges and the borrower can cancel
+func (s *Simulator) Return(party string, contractId string, body []byte) (string, error) {
+	return s.startAction(actionKinds["returns"], party, contractId, body)
+}
+
+// Recall recalls shares of an OPEN contract by its lender, which the
+// borrower acknowledges and the lender can cancel
+func (s *Simulator) Recall(party string, contractId string, body []byte) (string, error) {
+	return s.startAction(actionKinds["recalls"], party, contractId, body)
+}
+
+// Buyin proposes the buy-in of shares of an OPEN contract by its lender,
+// which the borrower accepts and the lender can cancel
+func (s *Simulator) Buyin(party string, contractId string, body []byte) (string, error) {
+	return s.startAction(actionKinds["buyins"], party, contractId, body)
+}
+
+// Act applies a transition, such as approve, acknowledge, accept or
+// cancel, to a rerate, return, recall or buyin in its first status
+func (s *Simulator) Act(party string, entity string, id string, name string) error {
+	kind, found := actionKinds[entity]
+	if !found {
+		return fmt.Errorf("%w: %s have no lifecycle", ErrNotFound, entity)
+	}
+
+	t, found := kind.transitions[name]
+	if !found {
+		return fmt.Errorf("%w: %s cannot be %s", ErrNotFound, entity, name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, found := s.actions[id]
+	if !found || a.kind != kind || !a.visibleTo(party) {
+		return fmt.Errorf("%w: %s %s", ErrNotFound, kind.idField, id)
+	}
+
+	if t.byInitiator != (party == a.initiator) {
+		if t.byInitiator {
+			return fmt.Errorf("%w: only the initiator of %s %s can do this", ErrForbidden, kind.idField, id)
+		}
+		return fmt.Errorf("%w: the initiator of %s %s cannot do this", ErrForbidden, kind.idField, id)
+	}
+
+	if a.status != kind.status {
+		return fmt.Errorf("%w: %s %s is %s, not %s", ErrConflict, kind.idField, id, a.status, kind.status)
+	}
+
+	s.transitionAction(a, party, t.status, t.event)
+
+	return nil
+}
+
+// Actions returns the rerates, returns, recalls or buyins visible to a
+// party and matching the list filters of query, oldest first
+func (s *Simulator) Actions(party string, entity string, query url.Values) []map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var visible []*action
+	for _, a := range s.actions {
+		if a.kind.entity == entity && a.visibleTo(party) && a.matches(query) {
+			visible = append(visible, a)
+		}
+	}
+	sort.Slice(visible, func(i, j int) bool {
+		return visible[i].created < visible[j].created
+	})
+
+	list := make([]map[string]any, len(visible))
+	for i, a := range visible {
+		list[i] = a.view()
+	}
+
+	return list
+}
+
+// Action returns a rerate, return, recall or buyin visible to a party
+func (s *Simulator) Action(party string, entity string, id string) (map[string]any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, found := s.actions[id]
+	if !found || a.kind.entity != entity || !a.visibleTo(party) {
+		return nil, fmt.Errorf("%w: %s %s", ErrNotFound, entity, id)
+	}
+
+	return a.view(), nil
+}
+
+// startAction records a new action on an OPEN contract and returns its id
+func (s *Simulator) startAction(kind *actionKind, party string, contractId string, body []byte) (string, error) {
+	fields := make(map[string]any)
+	if len(strings.TrimSpace(string(body))) > 0 {
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return "", fmt.Errorf("%w: body is not a JSON object", ErrInvalid)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, found := s.contracts[contractId]
+	if !found || !c.visibleTo(party) {
+		return "", fmt.Errorf("%w: contract %s", ErrNotFound, contractId)
+	}
+
+	switch {
+	case kind.initiator == roleLender && party != c.lender:
+		return "", fmt.Errorf("%w: only the lender of contract %s can start %s", ErrForbidden, contractId, kind.entity)
+	case kind.initiator == roleBorrower && party != c.borrower:
+		return "", fmt.Errorf("%w: only the borrower of contract %s can start %s", ErrForbidden, contractId, kind.entity)
+	case c.status != models.ContractStatusOpen:
+		return "", fmt.Errorf("%w: contract %s is %s, not %s", ErrConflict, contractId, c.status, models.ContractStatusOpen)
+	}
+
+	a := &action{
+		kind:       kind,
+		id:         newUUID(),
+		contractId: contractId,
+		initiator:  party,
+		lender:     c.lender,
+		borrower:   c.borrower,
+		ticker:     c.ticker,
+		isin:       c.isin,
+		fields:     fields,
+	}
+	s.actions[a.id] = a
+	s.transitionAction(a, party, kind.status, kind.event)
+	a.created = a.lastEventId
+
+	return a.id, nil
+}
+
+// transitionAction sets the status of an action and generates its event,
+// s.mu must be held
+func (s *Simulator) transitionAction(a *action, party string, status string, eventType string) {
+	e := s.addEvent(eventType, LedgerPath+a.kind.entity+"/"+a.id, a.lender, a.borrower)
+
+	a.status = status
+	a.lastEventId = e.EventId
+	a.lastParty = party
+	a.lastUpdate = s.Now().UTC()
+}
+
+// visibleTo reports whether a party is a transacting party of the
+// contract of the action
+func (a *action) visibleTo(party string) bool {
+	return party == a.lender || party == a.borrower
+}
+
+// view returns the action as served by the API, its request fields
+// with its state
+func (a *action) view() map[string]any {
+	view := make(map[string]any, len(a.fields)+6)
+	for k, v := range a.fields {
+		view[k] = v
+	}
+
+	view[a.kind.idField] = a.id
+	view["contractId"] = a.contractId
+	view[a.kind.statusField] = a.status
+	view["lastEventId"] = a.lastEventId
+	view["lastUpdatePartyId"] = a.lastParty
+	view["lastUpdateDateTime"] = a.lastUpdate.Format(time.RFC3339)
+
+	return view
+}
+
+// matches reports whether an action matches the list filters of query
+func (a *action) matches(query url.Values) bool {
+	party := query.Get("partyId")
+
+	return matchValue(query, "status", a.status) &&
+		(party == "" || strings.EqualFold(party, a.lender) || strings.EqualFold(party, a.borrower)) &&
+		matchValue(query, "ticker", a.ticker) &&
+		matchValue(query, "isin", a.isin) &&
+		matchTime(query, a.lastUpdate)
+}
@@ -0,0 +1,393 @@
+// Package simulator is a stateful, local stand-in for the 1Source REST API
+// and its KeyCloak login, for development and tests without network.
+package simulator
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Paths served by the simulator. A configuration file uses
+// <server>/auth as auth_url and <server>/v1/ledger as the base endpoint.
+const (
+	AuthPath   = "/auth/realms/"
+	LedgerPath = "/v1/ledger/"
+)
+
+// NewServer starts an httptest server running a new simulator. The
+// caller closes the server.
+func NewServer() (*Simulator, *httptest.Server) {
+	sim := New()
+	return sim, httptest.NewServer(sim)
+}
+
+// ServeHTTP serves the KeyCloak and ledger endpoints
+func (s *Simulator) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	slog.Debug("Simulator request", "method", r.Method, "url", r.URL.Path)
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, AuthPath):
+		s.serveAuth(w, r, strings.Split(strings.TrimPrefix(r.URL.Path, AuthPath), "/"))
+	case strings.HasPrefix(r.URL.Path, LedgerPath):
+		s.serveLedger(w, r, strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, LedgerPath), "/"), "/"))
+	default:
+		s.writeError(w, r, http.StatusNotFound, "no such resource")
+	}
+}
+
+// serveAuth serves the realm description and the token endpoint
+func (s *Simulator) serveAuth(w http.ResponseWriter, r *http.Request, segments []string) {
+	if segments[0] != Realm {
+		s.writeError(w, r, http.StatusNotFound, "Realm does not exist")
+		return
+	}
+
+	switch {
+	case len(segments) == 1 && r.Method == http.MethodGet:
+		issuer := "http://" + r.Host + AuthPath + Realm
+		writeJSON(w, http.StatusOK, map[string]any{
+			"realm":             Realm,
+			"public_key":        "",
+			"token-service":     issuer + "/protocol/openid-connect",
+			"account-service":   issuer + "/account",
+			"tokens-not-before": 0,
+		})
+
+	case strings.Join(segments[1:], "/") == "protocol/openid-connect/token" && r.Method == http.MethodPost:
+		s.serveToken(w, r)
+
+	default:
+		s.writeError(w, r, http.StatusNotFound, "no such resource")
+	}
+}
+
+// serveToken logs in with the password grant or refreshes a token,
+// answering as KeyCloak does
+func (s *Simulator) serveToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	var access, refresh string
+	var err error
+
+	switch r.PostForm.Get("grant_type") {
+	case "password":
+		access, refresh, err = s.Login(r.PostForm.Get("username"), r.PostForm.Get("password"))
+	case "refresh_token":
+		access, refresh, err = s.Refresh(r.PostForm.Get("refresh_token"))
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error":             "unsupported_grant_type",
+			"error_description": "Unsupported grant_type",
+		})
+		return
+	}
+
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{
+			"error":             "invalid_grant",
+			"error_description": "Invalid user credentials",
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token":       access,
+		"expires_in":         int(TokenLifetime.Seconds()),
+		"refresh_token":      refresh,
+		"refresh_expires_in": int(6 * TokenLifetime.Seconds()),
+		"token_type":         "Bearer",
+		"not-before-policy":  0,
+		"scope":              "profile email",
+	})
+}
+
+// serveLedger serves the ledger endpoints to an authenticated party
+func (s *Simulator) serveLedger(w http.ResponseWriter, r *http.Request, segments []string) {
+	scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	party, ok := s.authenticate(token)
+	if !strings.EqualFold(scheme, "Bearer") || !ok {
+		s.writeError(w, r, http.StatusUnauthorized, "a valid Bearer token is required")
+		return
+	}
+
+	entity := segments[0]
+	if entity == "contracts" {
+		s.serveContracts(w, r, party, segments[1:])
+		return
+	}
+	if kind, found := actionKinds[entity]; found {
+		s.serveActions(w, r, party, kind, segments[1:])
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		s.writeError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	switch {
+	case entity == "events" && len(segments) == 1:
+		writeList(w, r, s, s.Events(party, r.URL.Query()))
+
+	case entity == "events" && len(segments) == 2:
+		for _, e := range s.Events(party, nil) {
+			if segments[1] == strconv.FormatUint(e.EventId, 10) {
+				writeJSON(w, http.StatusOK, e)
+				return
+			}
+		}
+		s.writeError(w, r, http.StatusNotFound, "event "+segments[1]+" not found")
+
+	case len(segments) == 1:
+		writeList(w, r, s, s.list(entity))
+
+	case len(segments) == 2:
+		resource, found := s.get(entity, segments[1])
+		if !found {
+			s.writeError(w, r, http.StatusNotFound, entity+" "+segments[1]+" not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, resource)
+
+	default:
+		s.writeError(w, r, http.StatusNotFound, "no such resource")
+	}
+}
+
+// serveContracts serves the contract endpoints and lifecycle actions
+func (s *Simulator) serveContracts(w http.ResponseWriter, r *http.Request, party string, segments []string) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.writeError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	route := r.Method + " "
+	if len(segments) > 1 {
+		route += strings.Join(segments[1:], "/")
+	}
+
+	switch {
+	case len(segments) == 0 && r.Method == http.MethodGet:
+		writeList(w, r, s, s.Contracts(party, r.URL.Query()))
+
+	case len(segments) == 0 && r.Method == http.MethodPost:
+		id, err := s.Propose(party, body)
+		if err != nil {
+			s.writeLifecycleError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]any{
+			"timestamp":   s.Now().UTC().Format(time.RFC3339),
+			"status":      http.StatusCreated,
+			"message":     "Contract proposed",
+			"path":        r.URL.Path,
+			"resourceUri": LedgerPath + "contracts/" + id,
+		})
+
+	case len(segments) > 2:
+		s.writeError(w, r, http.StatusNotFound, "no such resource")
+
+	case route == "GET ":
+		contract, err := s.Contract(party, segments[0])
+		if err != nil {
+			s.writeLifecycleError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, contract)
+
+	case route == "GET history":
+		history, err := s.History(party, segments[0])
+		if err != nil {
+			s.writeLifecycleError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, history)
+
+	case route == "POST rerates", route == "POST returns", route == "POST recalls", route == "POST buyins":
+		kind := actionKinds[segments[1]]
+
+		id, err := s.startAction(kind, party, segments[0], body)
+		if err != nil {
+			s.writeLifecycleError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]any{
+			"timestamp":   s.Now().UTC().Format(time.RFC3339),
+			"status":      http.StatusCreated,
+			"message":     strings.TrimSuffix(kind.entity, "s") + " created",
+			"path":        r.URL.Path,
+			"resourceUri": LedgerPath + kind.entity + "/" + id,
+		})
+
+	case route == "POST cancel", route == "POST decline", route == "POST approve":
+		action := segments[1]
+
+		switch action {
+		case "cancel":
+			err = s.Cancel(party, segments[0])
+		case "decline":
+			err = s.Decline(party, segments[0])
+		case "approve":
+			err = s.Approve(party, segments[0], body)
+		}
+		if err != nil {
+			s.writeLifecycleError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, map[string]any{
+			"timestamp": s.Now().UTC().Format(time.RFC3339),
+			"status":    http.StatusOK,
+			"message":   "Contract " + action + " successful",
+			"path":      r.URL.Path,
+		})
+
+	default:
+		s.writeError(w, r, http.StatusNotFound, "no such resource")
+	}
+}
+
+// serveActions serves the rerate, return, recall and buyin endpoints and
+// their lifecycle actions. They are started from their contract, see
+// serveContracts.
+func (s *Simulator) serveActions(w http.ResponseWriter, r *http.Request, party string, kind *actionKind, segments []string) {
+	switch {
+	case len(segments) == 0 && r.Method == http.MethodGet:
+		writeList(w, r, s, s.Actions(party, kind.entity, r.URL.Query()))
+
+	case len(segments) == 1 && r.Method == http.MethodGet:
+		resource, err := s.Action(party, kind.entity, segments[0])
+		if err != nil {
+			s.writeLifecycleError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, resource)
+
+	case len(segments) == 2 && r.Method == http.MethodPost:
+		if err := s.Act(party, kind.entity, segments[0], segments[1]); err != nil {
+			s.writeLifecycleError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"timestamp": s.Now().UTC().Format(time.RFC3339),
+			"status":    http.StatusOK,
+			"message":   strings.TrimSuffix(kind.entity, "s") + " " + segments[1] + " successful",
+			"path":      r.URL.Path,
+		})
+
+	case len(segments) <= 2:
+		s.writeError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+
+	default:
+		s.writeError(w, r, http.StatusNotFound, "no such resource")
+	}
+}
+
+// list returns the resources of an entity type without a lifecycle,
+// ordered by id
+func (s *Simulator) list(entity string) []json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0, len(s.entities[entity]))
+	for id := range s.entities[entity] {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	list := make([]json.RawMessage, len(ids))
+	for i, id := range ids {
+		list[i] = s.entities[entity][id]
+	}
+
+	return list
+}
+
+// get returns a resource of an entity type without a lifecycle
+func (s *Simulator) get(entity string, id string) (json.RawMessage, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resource, found := s.entities[entity][id]
+	return resource, found
+}
+
+// writeList answers with a list, or with the page of it selected by the
+// page and size query parameters. Pages are numbered from 0.
+func writeList[T any](w http.ResponseWriter, r *http.Request, s *Simulator, list []T) {
+	query := r.URL.Query()
+	if !query.Has("size") {
+		writeJSON(w, http.StatusOK, list)
+		return
+	}
+
+	size, err := strconv.Atoi(query.Get("size"))
+	if err != nil || size < 1 {
+		s.writeError(w, r, http.StatusBadRequest, "invalid size "+query.Get("size"))
+		return
+	}
+
+	page := 0
+	if query.Has("page") {
+		if page, err = strconv.Atoi(query.Get("page")); err != nil || page < 0 {
+			s.writeError(w, r, http.StatusBadRequest, "invalid page "+query.Get("page"))
+			return
+		}
+	}
+
+	start := min(page*size, len(list))
+	end := min(start+size, len(list))
+
+	writeJSON(w, http.StatusOK, list[start:end])
+}
+
+// writeLifecycleError answers with the HTTP status of a lifecycle error
+func (s *Simulator) writeLifecycleError(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusInternalServerError
+
+	switch {
+	case errors.Is(err, ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrForbidden):
+		status = http.StatusForbidden
+	case errors.Is(err, ErrConflict):
+		status = http.StatusConflict
+	case errors.Is(err, ErrInvalid):
+		status = http.StatusBadRequest
+	}
+
+	s.writeError(w, r, status, err.Error())
+}
+
+// writeError answers with an error body shaped as the 1Source REST API's
+func (s *Simulator) writeError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	writeJSON(w, status, map[string]any{
+		"timestamp": s.Now().UTC().Format(time.RFC3339),
+		"status":    status,
+		"error":     http.StatusText(status),
+		"message":   message,
+		"path":      r.URL.Path,
+	})
+}
+
+// writeJSON answers with a JSON body
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		slog.Warn("Error writing simulator response", "error", err)
+	}
+}
@@ -0,0 +1,530 @@
+// Package simulator is a stateful, local stand-in for the 1Source REST API
+// and its KeyCloak login, for development and tests without network.
+//
+// It serves a KeyCloak compatible token endpoint and the ledger endpoints
+// of every entity type. Contracts go through the real lifecycle: proposed
+// by one party, then canceled by the proposer, or declined or approved
+// by the counterparty, each transition generating an event. Rerates,
+// returns, recalls and buyins are started on open contracts and go
+// through their own lifecycles the same way.
+//
+//	sim := simulator.New()
+//	server := httptest.NewServer(sim)
+//	defer server.Close()
+package simulator
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dharm-kapadia/1source-go/models"
+)
+
+// Realm is the KeyCloak realm served by the simulator
+const Realm = "1Source"
+
+// Event types generated by the contract lifecycle
+const (
+	EventContractProposed = "CONTRACT_PROPOSED"
+	EventContractCanceled = "CONTRACT_CANCELED"
+	EventContractDeclined = "CONTRACT_DECLINED"
+	EventContractOpened   = "CONTRACT_OPENED"
+)
+
+// Contract statuses set by the lifecycle, besides the models ones
+const (
+	ContractStatusCanceled = "CANCELED"
+	ContractStatusDeclined = "DECLINED"
+)
+
+// TokenLifetime is how long an access token is valid
+var TokenLifetime = 5 * time.Minute
+
+// User is a login of the simulator, acting for a party
+type User struct {
+	Username string
+	Password string
+	PartyId  string
+}
+
+// DefaultUsers are the logins of a new simulator, matching the parties of
+// the sample proposed_trade.json
+var DefaultUsers = []User{
+	{Username: "TestLender1User", Password: "password", PartyId: "TLEN-US"},
+	{Username: "TestBorrower1User", Password: "password", PartyId: "TBORR-US"},
+}
+
+// DefaultParties are the parties of a new simulator, those of the
+// DefaultUsers
+var DefaultParties = []map[string]string{
+	{"partyId": "TLEN-US", "partyName": "TestLender1", "gleifLei": "KTB500SKZSDI75VSFU40"},
+	{"partyId": "TBORR-US", "partyName": "TestBorrower1", "gleifLei": "KTB500SKZSDI75VSFU40"},
+}
+
+// Errors of the lifecycle, mapped to HTTP statuses by the server
+var (
+	ErrNotFound  = errors.New("not found")
+	ErrForbidden = errors.New("forbidden")
+	ErrConflict  = errors.New("conflict")
+	ErrInvalid   = errors.New("invalid request")
+)
+
+// Simulator holds the state of the simulated 1Source ledger. It is an
+// http.Handler.
+type Simulator struct {
+	// Now returns the current time, time.Now unless set
+	Now func() time.Time
+
+	mu        sync.Mutex
+	users     map[string]User
+	sessions  map[string]session
+	contracts map[string]*contract
+	actions   map[string]*action
+	events    []Event
+
+	// Entity types without a lifecycle, by id
+	entities map[string]map[string]json.RawMessage
+}
+
+// session is a token issued at login
+type session struct {
+	user    User
+	expires time.Time
+	refresh bool
+}
+
+// contract is the state of one contract
+type contract struct {
+	id          string
+	status      string
+	proposer    string
+	lender      string
+	borrower    string
+	ticker      string
+	isin        string
+	created     uint64
+	lastEventId uint64
+	lastParty   string
+	lastUpdate  time.Time
+	trade       json.RawMessage
+	settlement  []json.RawMessage
+	history     []json.RawMessage
+}
+
+// Event is a generated ledger event
+type Event struct {
+	EventId       uint64 `json:"eventId"`
+	EventType     string `json:"eventType"`
+	EventDateTime string `json:"eventDateTime"`
+	ResourceUri   string `json:"resourceUri"`
+
+	// Parties which can see the event
+	parties []string
+}
+
+// New returns a simulator with the DefaultUsers and DefaultParties and
+// an empty ledger
+func New() *Simulator {
+	s := &Simulator{
+		Now:       time.Now,
+		users:     make(map[string]User),
+		sessions:  make(map[string]session),
+		contracts: make(map[string]*contract),
+		actions:   make(map[string]*action),
+		entities:  make(map[string]map[string]json.RawMessage),
+	}
+
+	for _, u := range DefaultUsers {
+		s.AddUser(u)
+	}
+	for _, p := range DefaultParties {
+		_ = s.Add("parties", p["partyId"], p)
+	}
+
+	return s
+}
+
+// AddUser adds or replaces a login
+func (s *Simulator) AddUser(u User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users[u.Username] = u
+}
+
+// Add stores a resource of an entity type without a lifecycle, such as
+// a party or an agreement, so it can be listed and retrieved
+func (s *Simulator) Add(entity string, id string, resource any) error {
+	b, err := json.Marshal(resource)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.entities[entity] == nil {
+		s.entities[entity] = make(map[string]json.RawMessage)
+	}
+	s.entities[entity][id] = b
+
+	return nil
+}
+
+// Login checks the credentials of a user and returns an access token and
+// a refresh token
+func (s *Simulator) Login(username string, password string) (string, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, found := s.users[username]
+	if !found || u.Password != password {
+		return "", "", fmt.Errorf("%w: invalid user credentials", ErrForbidden)
+	}
+
+	return s.issue(u)
+}
+
+// Refresh exchanges a refresh token for new tokens
+func (s *Simulator) Refresh(refreshToken string) (string, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, found := s.sessions[refreshToken]
+	if !found || !sess.refresh || s.Now().After(sess.expires) {
+		return "", "", fmt.Errorf("%w: invalid refresh token", ErrForbidden)
+	}
+	delete(s.sessions, refreshToken)
+
+	return s.issue(sess.user)
+}
+
+// issue creates the tokens of a session, s.mu must be held
+func (s *Simulator) issue(u User) (string, string, error) {
+	access, refresh := newId(), newId()
+	now := s.Now()
+
+	s.sessions[access] = session{user: u, expires: now.Add(TokenLifetime)}
+	s.sessions[refresh] = session{user: u, expires: now.Add(6 * TokenLifetime), refresh: true}
+
+	return access, refresh, nil
+}
+
+// authenticate returns the party of a valid access token
+func (s *Simulator) authenticate(token string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, found := s.sessions[token]
+	if !found || sess.refresh || s.Now().After(sess.expires) {
+		return "", false
+	}
+
+	return sess.user.PartyId, true
+}
+
+// Propose records a contract proposed by party and returns its id. The
+// party must be one of the transacting parties of the proposal.
+func (s *Simulator) Propose(party string, body []byte) (string, error) {
+	var raw struct {
+		Trade      json.RawMessage   `json:"trade"`
+		Settlement []json.RawMessage `json:"settlement"`
+	}
+	if err := json.Unmarshal(body, &raw); err != nil || len(raw.Trade) == 0 {
+		return "", fmt.Errorf("%w: body is not a contract proposal", ErrInvalid)
+	}
+
+	var proposal models.ContractProposal
+	if err := json.Unmarshal(body, &proposal); err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalid, err)
+	}
+
+	lender, borrower := models.Contract{Trade: proposal.Trade}.Parties()
+	if lender == "" || borrower == "" {
+		return "", fmt.Errorf("%w: a LENDER and a BORROWER transacting party are required", ErrInvalid)
+	}
+	if party != lender && party != borrower {
+		return "", fmt.Errorf("%w: party %s is not a transacting party of the proposal", ErrForbidden, party)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := &contract{
+		id:         newUUID(),
+		status:     models.ContractStatusProposed,
+		proposer:   party,
+		lender:     lender,
+		borrower:   borrower,
+		ticker:     proposal.Trade.Instrument.Ticker,
+		isin:       proposal.Trade.Instrument.Isin,
+		trade:      raw.Trade,
+		settlement: raw.Settlement,
+	}
+	s.contracts[c.id] = c
+	s.transition(c, party, models.ContractStatusProposed, EventContractProposed)
+	c.created = c.lastEventId
+
+	return c.id, nil
+}
+
+// Cancel cancels a PROPOSED contract, which only its proposer can do
+func (s *Simulator) Cancel(party string, id string) error {
+	return s.change(party, id, true, ContractStatusCanceled, EventContractCanceled, nil)
+}
+
+// Decline declines a PROPOSED contract, which only the counterparty of
+// the proposer can do
+func (s *Simulator) Decline(party string, id string) error {
+	return s.change(party, id, false, ContractStatusDeclined, EventContractDeclined, nil)
+}
+
+// Approve approves a PROPOSED contract, which only the counterparty of
+// the proposer can do, opening it. The body may hold the settlement
+// instruction of the approving party.
+func (s *Simulator) Approve(party string, id string, body []byte) error {
+	var approval struct {
+		Settlement json.RawMessage `json:"settlement"`
+	}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &approval); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalid, err)
+		}
+	}
+
+	return s.change(party, id, false, models.ContractStatusOpen, EventContractOpened, approval.Settlement)
+}
+
+// change moves a PROPOSED contract to a new status
+func (s *Simulator) change(party string, id string, byProposer bool, status string, eventType string, settlement json.RawMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, found := s.contracts[id]
+	if !found || !c.visibleTo(party) {
+		return fmt.Errorf("%w: contract %s", ErrNotFound, id)
+	}
+
+	if byProposer != (party == c.proposer) {
+		if byProposer {
+			return fmt.Errorf("%w: only the proposer of contract %s can do this", ErrForbidden, id)
+		}
+		return fmt.Errorf("%w: the proposer of contract %s cannot do this", ErrForbidden, id)
+	}
+
+	if c.status != models.ContractStatusProposed {
+		return fmt.Errorf("%w: contract %s is %s, not %s", ErrConflict, id, c.status, models.ContractStatusProposed)
+	}
+
+	if len(settlement) > 0 {
+		c.settlement = append(c.settlement, settlement)
+	}
+	s.transition(c, party, status, eventType)
+
+	return nil
+}
+
+// transition sets the status of a contract, generates its event and
+// records the new version in its history, s.mu must be held
+func (s *Simulator) transition(c *contract, party string, status string, eventType string) {
+	e := s.addEvent(eventType, LedgerPath+"contracts/"+c.id, c.lender, c.borrower)
+
+	c.status = status
+	c.lastEventId = e.EventId
+	c.lastParty = party
+	c.lastUpdate = s.Now().UTC()
+
+	version, _ := json.Marshal(c.view())
+	c.history = append(c.history, version)
+}
+
+// addEvent generates an event on a resource, seen by parties, s.mu must
+// be held
+func (s *Simulator) addEvent(eventType string, resourceUri string, parties ...string) Event {
+	e := Event{
+		EventId:       uint64(len(s.events) + 1),
+		EventType:     eventType,
+		EventDateTime: s.Now().UTC().Format(time.RFC3339),
+		ResourceUri:   resourceUri,
+		parties:       parties,
+	}
+	s.events = append(s.events, e)
+
+	return e
+}
+
+// visibleTo reports whether a party is a transacting party of the contract
+func (c *contract) visibleTo(party string) bool {
+	return party == c.lender || party == c.borrower
+}
+
+// view returns the contract as served by the API
+func (c *contract) view() map[string]any {
+	settlement := c.settlement
+	if settlement == nil {
+		settlement = []json.RawMessage{}
+	}
+
+	return map[string]any{
+		"contractId":         c.id,
+		"lastEventId":        c.lastEventId,
+		"contractStatus":     c.status,
+		"settlementStatus":   "NONE",
+		"lastUpdatePartyId":  c.lastParty,
+		"lastUpdateDateTime": c.lastUpdate.Format(time.RFC3339),
+		"trade":              c.trade,
+		"settlement":         settlement,
+	}
+}
+
+// Contracts returns the contracts visible to a party and matching the
+// list filters of query, oldest first
+func (s *Simulator) Contracts(party string, query url.Values) []map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var visible []*contract
+	for _, c := range s.contracts {
+		if c.visibleTo(party) && c.matches(query) {
+			visible = append(visible, c)
+		}
+	}
+	sort.Slice(visible, func(i, j int) bool {
+		return visible[i].created < visible[j].created
+	})
+
+	list := make([]map[string]any, len(visible))
+	for i, c := range visible {
+		list[i] = c.view()
+	}
+
+	return list
+}
+
+// Contract returns a contract visible to a party
+func (s *Simulator) Contract(party string, id string) (map[string]any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, found := s.contracts[id]
+	if !found || !c.visibleTo(party) {
+		return nil, fmt.Errorf("%w: contract %s", ErrNotFound, id)
+	}
+
+	return c.view(), nil
+}
+
+// History returns the versions of a contract visible to a party
+func (s *Simulator) History(party string, id string) ([]json.RawMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, found := s.contracts[id]
+	if !found || !c.visibleTo(party) {
+		return nil, fmt.Errorf("%w: contract %s", ErrNotFound, id)
+	}
+
+	return append([]json.RawMessage(nil), c.history...), nil
+}
+
+// Events returns the events visible to a party and matching the list
+// filters of query, oldest first
+func (s *Simulator) Events(party string, query url.Values) []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := []Event{}
+	for _, e := range s.events {
+		if !e.matches(query) {
+			continue
+		}
+
+		for _, p := range e.parties {
+			if p == party {
+				list = append(list, e)
+				break
+			}
+		}
+	}
+
+	return list
+}
+
+// matches reports whether a contract matches the list filters of query
+func (c *contract) matches(query url.Values) bool {
+	party := query.Get("partyId")
+
+	return matchValue(query, "status", c.status) &&
+		(party == "" || strings.EqualFold(party, c.lender) || strings.EqualFold(party, c.borrower)) &&
+		matchValue(query, "ticker", c.ticker) &&
+		matchValue(query, "isin", c.isin) &&
+		matchTime(query, c.lastUpdate)
+}
+
+// matches reports whether an event matches the list filters of query
+func (e Event) matches(query url.Values) bool {
+	t, _ := time.Parse(time.RFC3339, e.EventDateTime)
+
+	return matchValue(query, "eventType", e.EventType) && matchTime(query, t)
+}
+
+// matchValue reports whether a value matches the query parameter name,
+// when it is set
+func matchValue(query url.Values, name string, value string) bool {
+	return !query.Has(name) || strings.EqualFold(query.Get(name), value)
+}
+
+// matchTime reports whether t is within the since and before query
+// parameters, when they are set. Invalid values match nothing.
+func matchTime(query url.Values, t time.Time) bool {
+	if query.Has("since") {
+		since, err := parseTime(query.Get("since"))
+		if err != nil || t.Before(since) {
+			return false
+		}
+	}
+
+	if query.Has("before") {
+		before, err := parseTime(query.Get("before"))
+		if err != nil || !t.Before(before) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseTime parses a date as YYYY-MM-DD or a date and time as RFC 3339
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
+
+// newId returns 16 random bytes in hex, used as tokens
+func newId() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// newUUID returns a random version 4 UUID, used as contract ids
+func newUUID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
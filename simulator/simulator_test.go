@@ -0,0 +1,330 @@
+package simulator
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/dharm-kapadia/1source-go/api"
+	"github.com/dharm-kapadia/1source-go/models"
+)
+
+// login returns the Authorization value of a user of the simulator at url
+func login(t *testing.T, url string, username string) string {
+	t.Helper()
+
+	var cfg models.AppConfig
+	cfg.General.Auth_URL = url + "/auth"
+	cfg.General.Realm_Name = Realm
+	cfg.Authentication.Client_Id = "canton-participant1-client"
+	cfg.Authentication.Username = username
+	cfg.Authentication.Password = "password"
+
+	token, err := api.GetAuthToken(&cfg)
+	if err != nil {
+		t.Fatalf("GetAuthToken(%s) error = %v", username, err)
+	}
+
+	return "Bearer " + token.AccessToken
+}
+
+// contractStatus returns the status of a contract as seen by bearer
+func contractStatus(t *testing.T, url string, id string, bearer string) string {
+	t.Helper()
+
+	data, err := api.Get(url+LedgerPath+"contracts/"+id, bearer)
+	if err != nil {
+		t.Fatalf("Get(contract %s) error = %v", id, err)
+	}
+
+	var contract models.Contract
+	if err := json.Unmarshal([]byte(data), &contract); err != nil {
+		t.Fatalf("contract %s cannot be decoded: %v", id, err)
+	}
+
+	return contract.ContractStatus
+}
+
+func TestContractLifecycle(t *testing.T) {
+	_, server := NewServer()
+	defer server.Close()
+
+	proposal, err := os.ReadFile("../proposed_trade.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lender := login(t, server.URL, "TestLender1User")
+	borrower := login(t, server.URL, "TestBorrower1User")
+	contracts := server.URL + LedgerPath + "contracts"
+
+	propose := func() string {
+		t.Helper()
+
+		cir, err := api.ProposeContract(contracts, lender, proposal)
+		if err != nil {
+			t.Fatalf("ProposeContract() error = %v", err)
+		}
+		if contractStatus(t, server.URL, cir.ContractId(), borrower) != models.ContractStatusProposed {
+			t.Fatalf("contract %s is not PROPOSED to the borrower", cir.ContractId())
+		}
+		return cir.ContractId()
+	}
+
+	expectStatus := func(err error, status int) {
+		t.Helper()
+
+		var statusErr *api.StatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != status {
+			t.Errorf("error = %v, expected HTTP status %d", err, status)
+		}
+	}
+
+	// Only the proposer cancels, and only once
+	canceled := propose()
+	_, err = api.PostCancelContract(contracts+"/"+canceled+"/cancel", borrower)
+	expectStatus(err, http.StatusForbidden)
+	if _, err := api.PostCancelContract(contracts+"/"+canceled+"/cancel", lender); err != nil {
+		t.Fatalf("PostCancelContract() error = %v", err)
+	}
+	_, err = api.PostCancelContract(contracts+"/"+canceled+"/cancel", lender)
+	expectStatus(err, http.StatusConflict)
+	if status := contractStatus(t, server.URL, canceled, lender); status != ContractStatusCanceled {
+		t.Errorf("canceled contract status = %s", status)
+	}
+
+	// Only the counterparty declines
+	declined := propose()
+	_, err = api.PostDeclineContract(contracts+"/"+declined+"/decline", lender)
+	expectStatus(err, http.StatusForbidden)
+	if _, err := api.PostDeclineContract(contracts+"/"+declined+"/decline", borrower); err != nil {
+		t.Fatalf("PostDeclineContract() error = %v", err)
+	}
+	if status := contractStatus(t, server.URL, declined, lender); status != ContractStatusDeclined {
+		t.Errorf("declined contract status = %s", status)
+	}
+
+	// Approving opens the contract
+	approved := propose()
+	if _, err := api.Post(contracts+"/"+approved+"/approve", borrower, nil, http.StatusOK, "approving contract"); err != nil {
+		t.Fatalf("approve error = %v", err)
+	}
+	if status := contractStatus(t, server.URL, approved, lender); status != models.ContractStatusOpen {
+		t.Errorf("approved contract status = %s", status)
+	}
+
+	_, err = api.Get(contracts+"/unknown", lender)
+	expectStatus(err, http.StatusNotFound)
+
+	// Every transition generated an event seen by both parties
+	data, err := api.Get(server.URL+LedgerPath+"events", borrower)
+	if err != nil {
+		t.Fatalf("Get(events) error = %v", err)
+	}
+
+	var events models.Events
+	if err := json.Unmarshal([]byte(data), &events); err != nil {
+		t.Fatal(err)
+	}
+
+	var types []string
+	for _, e := range events {
+		types = append(types, e.EventType)
+	}
+	expected := []string{
+		EventContractProposed, EventContractCanceled,
+		EventContractProposed, EventContractDeclined,
+		EventContractProposed, EventContractOpened,
+	}
+	if len(types) != len(expected) {
+		t.Fatalf("event types = %v, expected %v", types, expected)
+	}
+	for i := range expected {
+		if types[i] != expected[i] {
+			t.Errorf("event %d type = %s, expected %s", i+1, types[i], expected[i])
+		}
+	}
+
+	history, err := api.Get(contracts+"/"+approved+"/history", lender)
+	if err != nil {
+		t.Fatalf("Get(history) error = %v", err)
+	}
+	var versions []models.Contract
+	if err := json.Unmarshal([]byte(history), &versions); err != nil || len(versions) != 2 {
+		t.Errorf("history = %s, expected 2 versions", history)
+	}
+}
+
+func TestLoginRefused(t *testing.T) {
+	_, server := NewServer()
+	defer server.Close()
+
+	var cfg models.AppConfig
+	cfg.General.Auth_URL = server.URL + "/auth"
+	cfg.General.Realm_Name = Realm
+	cfg.Authentication.Username = "TestLender1User"
+	cfg.Authentication.Password = "wrong"
+
+	if _, err := api.GetAuthToken(&cfg); !errors.Is(err, api.ErrAuth) {
+		t.Errorf("GetAuthToken() error = %v, expected ErrAuth", err)
+	}
+
+	if _, err := api.Get(server.URL+LedgerPath+"contracts", "Bearer invalid"); err == nil {
+		t.Error("Get() with an invalid token succeeded")
+	}
+}
+
+func TestActionLifecycle(t *testing.T) {
+	_, server := NewServer()
+	defer server.Close()
+
+	proposal, err := os.ReadFile("../proposed_trade.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lender := login(t, server.URL, "TestLender1User")
+	borrower := login(t, server.URL, "TestBorrower1User")
+	ledger := server.URL + LedgerPath
+
+	cir, err := api.ProposeContract(ledger+"contracts", lender, proposal)
+	if err != nil {
+		t.Fatalf("ProposeContract() error = %v", err)
+	}
+	contract := ledger + "contracts/" + cir.ContractId()
+
+	expectStatus := func(err error, status int) {
+		t.Helper()
+
+		var statusErr *api.StatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != status {
+			t.Errorf("error = %v, expected HTTP status %d", err, status)
+		}
+	}
+
+	// start starts an action on the contract and returns its URL
+	start := func(entity string, bearer string, body string) (string, error) {
+		t.Helper()
+
+		resp, err := api.Post(contract+"/"+entity, bearer, []byte(body), http.StatusCreated, "starting "+entity)
+		if err != nil {
+			return "", err
+		}
+
+		var created struct{ ResourceUri string }
+		if err := json.Unmarshal(resp, &created); err != nil {
+			t.Fatal(err)
+		}
+		return server.URL + created.ResourceUri, nil
+	}
+
+	act := func(resource string, name string, bearer string) error {
+		_, err := api.Post(resource+"/"+name, bearer, nil, http.StatusOK, name)
+		return err
+	}
+
+	status := func(resource string, field string) string {
+		t.Helper()
+
+		data, err := api.Get(resource, lender)
+		if err != nil {
+			t.Fatalf("Get(%s) error = %v", resource, err)
+		}
+		var view map[string]any
+		if err := json.Unmarshal([]byte(data), &view); err != nil {
+			t.Fatal(err)
+		}
+		return view[field].(string)
+	}
+
+	// Nothing can be started on a contract which is not OPEN
+	_, err = start("rerates", lender, `{"rate":{"rebate":{"fixed":{"baseRate":0.1}}}}`)
+	expectStatus(err, http.StatusConflict)
+
+	if _, err := api.Post(contract+"/approve", borrower, nil, http.StatusOK, "approving contract"); err != nil {
+		t.Fatalf("approve error = %v", err)
+	}
+
+	// A rerate is approved by the counterparty only, once
+	rerate, err := start("rerates", lender, `{"rate":{"rebate":{"fixed":{"baseRate":0.1}}}}`)
+	if err != nil {
+		t.Fatalf("rerate error = %v", err)
+	}
+	expectStatus(act(rerate, "approve", lender), http.StatusForbidden)
+	if err := act(rerate, "approve", borrower); err != nil {
+		t.Fatalf("approve rerate error = %v", err)
+	}
+	expectStatus(act(rerate, "decline", borrower), http.StatusConflict)
+	if s := status(rerate, "rerateStatus"); s != StatusApplied {
+		t.Errorf("approved rerate status = %s", s)
+	}
+
+	// Returns are started by the borrower, recalls and buyins by the lender
+	_, err = start("returns", lender, `{"quantity":100}`)
+	expectStatus(err, http.StatusForbidden)
+	ret, err := start("returns", borrower, `{"quantity":100}`)
+	if err != nil {
+		t.Fatalf("return error = %v", err)
+	}
+	if err := act(ret, "acknowledge", lender); err != nil {
+		t.Fatalf("acknowledge return error = %v", err)
+	}
+
+	_, err = start("recalls", borrower, `{"quantity":50}`)
+	expectStatus(err, http.StatusForbidden)
+	recall, err := start("recalls", lender, `{"quantity":50}`)
+	if err != nil {
+		t.Fatalf("recall error = %v", err)
+	}
+	expectStatus(act(recall, "cancel", borrower), http.StatusForbidden)
+	if err := act(recall, "cancel", lender); err != nil {
+		t.Fatalf("cancel recall error = %v", err)
+	}
+
+	buyin, err := start("buyins", lender, `{"quantity":50}`)
+	if err != nil {
+		t.Fatalf("buyin error = %v", err)
+	}
+	if err := act(buyin, "accept", borrower); err != nil {
+		t.Fatalf("accept buyin error = %v", err)
+	}
+	if s := status(buyin, "buyinStatus"); s != StatusAccepted {
+		t.Errorf("accepted buyin status = %s", s)
+	}
+
+	// Lists are filtered, and every transition generated an event
+	data, err := api.Get(ledger+"recalls?status="+StatusCanceled, borrower)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var recalls []map[string]any
+	if err := json.Unmarshal([]byte(data), &recalls); err != nil || len(recalls) != 1 || recalls[0]["quantity"] != 50.0 {
+		t.Errorf("canceled recalls = %s", data)
+	}
+
+	data, err = api.Get(ledger+"events", borrower)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events models.Events
+	if err := json.Unmarshal([]byte(data), &events); err != nil {
+		t.Fatal(err)
+	}
+
+	var types []string
+	for _, e := range events[2:] {
+		types = append(types, e.EventType)
+	}
+	expected := []string{
+		EventRerateProposed, EventRerateApplied,
+		EventReturnPending, EventReturnAcknowledged,
+		EventRecallOpened, EventRecallCanceled,
+		EventBuyinProposed, EventBuyinAccepted,
+	}
+	if strings.Join(types, ",") != strings.Join(expected, ",") {
+		t.Errorf("event types = %v, expected %v", types, expected)
+	}
+}
@@ -0,0 +1,96 @@
+// Package snapshot captures every entity list of the 1Source REST API at
+// one point in time, for audit and debugging.
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ArchiveExt is the extension of snapshot archives
+const ArchiveExt = ".tar.gz"
+
+// Archive packs the snapshot directory at path into path.tar.gz, with
+// its files under the snapshot name, and removes the directory. It
+// returns the path of the archive.
+func Archive(path string) (string, error) {
+	archive := path + ArchiveExt
+	tmp := archive + ".tmp"
+
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", err
+	}
+
+	err = writeArchive(f, path)
+	err = errors.Join(err, f.Close())
+	if err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+
+	if err := os.Rename(tmp, archive); err != nil {
+		return "", err
+	}
+
+	return archive, os.RemoveAll(path)
+}
+
+// writeArchive writes the files of a snapshot directory as a gzipped tar
+func writeArchive(w io.Writer, path string) error {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	for _, name := range names {
+		if err := addFile(tw, filepath.Join(path, name), filepath.Base(path)+"/"+name); err != nil {
+			return err
+		}
+	}
+
+	return errors.Join(tw.Close(), gz.Close())
+}
+
+// addFile adds a file to a tar archive under name
+func addFile(tw *tar.Writer, file string, name string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name
+
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tw, f)
+	return err
+}
@@ -0,0 +1,253 @@
+// Package snapshot captures every entity list of the 1Source REST API at
+// one point in time, for audit and debugging.
+package snapshot
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DiffEntities lists the entity types compared by Compare, in order
+var DiffEntities = []string{"contracts", "agreements", "rerates", "returns", "recalls", "buyins"}
+
+// IDFields holds the field identifying the entities of each type
+var IDFields = map[string]string{
+	"events":     "eventId",
+	"parties":    "partyId",
+	"agreements": "agreementId",
+	"contracts":  "contractId",
+	"rerates":    "rerateId",
+	"returns":    "returnId",
+	"recalls":    "recallId",
+	"buyins":     "buyinId",
+}
+
+// Change is the change of one field of an entity. Field is the path of
+// the field, such as trade.collateral.collateralValue or
+// transactingParties[0].partyRole. Old and New hold its JSON values, and
+// are empty when the field was added or removed.
+type Change struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// Changed is an entity present in both snapshots with different fields
+type Changed struct {
+	Id      string
+	Changes []Change
+}
+
+// EntityDiff lists the differences of one entity type between two
+// snapshots, sorted by id. Error is set when the type could not be
+// compared, such as when a snapshot failed to read it.
+type EntityDiff struct {
+	Name      string
+	Added     []string
+	Removed   []string
+	Changed   []Changed
+	Unchanged int
+	Error     string
+}
+
+// Compare lists the entities of each type added, removed and changed from
+// one snapshot to the next
+func Compare(from *Snapshot, to *Snapshot, entities []string) []EntityDiff {
+	diffs := make([]EntityDiff, len(entities))
+
+	for i, name := range entities {
+		diffs[i].Name = name
+
+		if err := compareEntity(&diffs[i], from, to); err != nil {
+			diffs[i] = EntityDiff{Name: name, Error: err.Error()}
+		}
+	}
+
+	return diffs
+}
+
+// compareEntity compares the entities of one type of two snapshots
+func compareEntity(diff *EntityDiff, from *Snapshot, to *Snapshot) error {
+	old, err := index(from, diff.Name)
+	if err != nil {
+		return err
+	}
+	current, err := index(to, diff.Name)
+	if err != nil {
+		return err
+	}
+
+	for id, entity := range current {
+		previous, found := old[id]
+		if !found {
+			diff.Added = append(diff.Added, id)
+			continue
+		}
+
+		if changes := compareFields(previous, entity); len(changes) > 0 {
+			diff.Changed = append(diff.Changed, Changed{Id: id, Changes: changes})
+		} else {
+			diff.Unchanged++
+		}
+	}
+
+	for id := range old {
+		if _, found := current[id]; !found {
+			diff.Removed = append(diff.Removed, id)
+		}
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Slice(diff.Changed, func(i, j int) bool { return diff.Changed[i].Id < diff.Changed[j].Id })
+
+	return nil
+}
+
+// index returns the entities of a type of a snapshot, flattened into
+// their fields and keyed by id
+func index(s *Snapshot, name string) (map[string]map[string]string, error) {
+	idField := IDFields[name]
+	entities := make(map[string]map[string]string)
+
+	i := 0
+	err := s.Each(name, func(raw json.RawMessage) error {
+		i++
+
+		var value any
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); err != nil {
+			return fmt.Errorf("%s %d of '%s': %w", name, i, s.Name(), err)
+		}
+
+		id, found := entityId(value, idField)
+		if !found {
+			return fmt.Errorf("%s %d of '%s' has no %s", name, i, s.Name(), idField)
+		}
+
+		fields := make(map[string]string)
+		flatten("", value, fields)
+		entities[id] = fields
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entities, nil
+}
+
+// entityId returns the id of an entity. The id field is matched without
+// regard to case, as in buyinId and buyInId.
+func entityId(value any, field string) (string, bool) {
+	object, ok := value.(map[string]any)
+	if !ok || field == "" {
+		return "", false
+	}
+
+	for key, id := range object {
+		if !strings.EqualFold(key, field) {
+			continue
+		}
+
+		switch id := id.(type) {
+		case string:
+			return id, id != ""
+		case json.Number:
+			return id.String(), true
+		}
+	}
+
+	return "", false
+}
+
+// flatten stores the JSON value of every leaf field of value under its
+// path. Empty objects and arrays are leaves.
+func flatten(path string, value any, fields map[string]string) {
+	switch v := value.(type) {
+	case map[string]any:
+		if len(v) > 0 {
+			for key, child := range v {
+				if path == "" {
+					flatten(key, child, fields)
+				} else {
+					flatten(path+"."+key, child, fields)
+				}
+			}
+			return
+		}
+	case []any:
+		if len(v) > 0 {
+			for i, child := range v {
+				flatten(path+"["+strconv.Itoa(i)+"]", child, fields)
+			}
+			return
+		}
+	}
+
+	b, _ := json.Marshal(value)
+	fields[path] = string(b)
+}
+
+// compareFields returns the changed fields of two flattened entities,
+// sorted by path
+func compareFields(old map[string]string, current map[string]string) []Change {
+	var changes []Change
+
+	for field, value := range current {
+		if previous := old[field]; previous != value {
+			changes = append(changes, Change{Field: field, Old: previous, New: value})
+		}
+	}
+	for field, previous := range old {
+		if _, found := current[field]; !found {
+			changes = append(changes, Change{Field: field, Old: previous})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// WriteCSV writes the differences as CSV, one row per added or removed
+// entity and per changed field. String values are written unquoted.
+func WriteCSV(w io.Writer, diffs []EntityDiff) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write([]string{"entity", "id", "change", "field", "old", "new"}); err != nil {
+		return err
+	}
+
+	for _, d := range diffs {
+		for _, id := range d.Added {
+			cw.Write([]string{d.Name, id, "new", "", "", ""})
+		}
+		for _, id := range d.Removed {
+			cw.Write([]string{d.Name, id, "removed", "", "", ""})
+		}
+		for _, c := range d.Changed {
+			for _, change := range c.Changes {
+				cw.Write([]string{d.Name, c.Id, "changed", change.Field, csvValue(change.Old), csvValue(change.New)})
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvValue returns a JSON value as written in CSV, strings unquoted
+func csvValue(value string) string {
+	var s string
+	if strings.HasPrefix(value, `"`) && json.Unmarshal([]byte(value), &s) == nil {
+		return s
+	}
+	return value
+}
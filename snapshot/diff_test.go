@@ -0,0 +1,151 @@
+package snapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// listFetch serves fixed entity lists
+func listFetch(lists map[string][]string) Fetch {
+	return func(entity string, fn func(json.RawMessage) error) (int, error) {
+		for i, e := range lists[entity] {
+			if err := fn(json.RawMessage(e)); err != nil {
+				return i, err
+			}
+		}
+		return len(lists[entity]), nil
+	}
+}
+
+// takeAt takes a snapshot of lists under the name of a fixed time
+func takeAt(t *testing.T, name string, lists map[string][]string) string {
+	t.Helper()
+
+	path, _, err := Take([]string{"contracts", "recalls"}, listFetch(lists), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	renamed := filepath.Join(Dir, name)
+	if err := os.Rename(path, renamed); err != nil {
+		t.Fatal(err)
+	}
+	return renamed
+}
+
+func TestCompare(t *testing.T) {
+	Dir = t.TempDir()
+
+	takeAt(t, "snapshot-20240101T180000Z", map[string][]string{
+		"contracts": {
+			`{"contractId":"a","contractStatus":"PROPOSED","trade":{"quantity":100,"parties":[{"role":"LENDER"}]}}`,
+			`{"contractId":"b","contractStatus":"OPEN"}`,
+			`{"contractId":"c","contractStatus":"OPEN","note":"x"}`,
+		},
+		"recalls": {`{"recallId":7}`},
+	})
+	to := takeAt(t, "snapshot-20240102T070000Z", map[string][]string{
+		"contracts": {
+			`{"contractId":"d","contractStatus":"PROPOSED"}`,
+			`{"contractId":"c","contractStatus":"OPEN","note":"x"}`,
+			`{"contractId":"a","contractStatus":"OPEN","trade":{"quantity":100,"parties":[{"role":"BORROWER"}]},"version":2}`,
+		},
+		"recalls": {`{"recallId":7}`},
+	})
+
+	// The archive of the second snapshot is read the same way
+	if _, err := Archive(to); err != nil {
+		t.Fatal(err)
+	}
+
+	from, err := Open("2024-01-01")
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	current, err := Open("snapshot-20240102T070000Z")
+	if err != nil {
+		t.Fatalf("Open() of an archive failed: %v", err)
+	}
+
+	diffs := Compare(from, current, []string{"contracts", "recalls", "buyins"})
+
+	contracts := diffs[0]
+	expected := []Changed{{Id: "a", Changes: []Change{
+		{Field: "contractStatus", Old: `"PROPOSED"`, New: `"OPEN"`},
+		{Field: "trade.parties[0].role", Old: `"LENDER"`, New: `"BORROWER"`},
+		{Field: "version", New: "2"},
+	}}}
+	if !reflect.DeepEqual(contracts.Added, []string{"d"}) || !reflect.DeepEqual(contracts.Removed, []string{"b"}) ||
+		!reflect.DeepEqual(contracts.Changed, expected) || contracts.Unchanged != 1 {
+		t.Errorf("Compare() contracts = %+v", contracts)
+	}
+
+	if diffs[1].Unchanged != 1 || diffs[1].Error != "" {
+		t.Errorf("Compare() recalls = %+v", diffs[1])
+	}
+	if diffs[2].Error == "" {
+		t.Errorf("Compare() of a type missing from the snapshots succeeded")
+	}
+
+	var b bytes.Buffer
+	if err := WriteCSV(&b, diffs[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "contracts,a,changed,contractStatus,PROPOSED,OPEN\n") {
+		t.Errorf("WriteCSV() = %s", b.String())
+	}
+}
+
+func TestEachChecksum(t *testing.T) {
+	Dir = t.TempDir()
+
+	path := takeAt(t, "snapshot-20240101T180000Z", map[string][]string{"contracts": {`{"contractId":"a"}`}})
+
+	f, err := os.OpenFile(filepath.Join(path, "contracts.ndjson"), os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"contractId":"b"}` + "\n")
+	f.Close()
+
+	// Only the files read are checked
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	if err := os.Remove(filepath.Join(path, "recalls.ndjson")); err != nil {
+		t.Fatal(err)
+	}
+
+	count := 0
+	err = s.Each("contracts", func(json.RawMessage) error {
+		count++
+		return nil
+	})
+	if count != 2 || err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("Each() of a changed file = %d entities, %v, expected a checksum error", count, err)
+	}
+}
+
+func TestOpenIncomplete(t *testing.T) {
+	Dir = t.TempDir()
+
+	path, _, err := Take([]string{"contracts"}, fakeFetch(1, "contracts"), "")
+	if !errors.Is(err, ErrIncomplete) {
+		t.Fatal(err)
+	}
+
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	if err := s.Each("contracts", func(json.RawMessage) error { return nil }); !errors.Is(err, ErrIncomplete) {
+		t.Errorf("Each() of a failed type = %v, expected ErrIncomplete", err)
+	}
+}
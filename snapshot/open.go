@@ -0,0 +1,201 @@
+// Package snapshot captures every entity list of the 1Source REST API at
+// one point in time, for audit and debugging.
+package snapshot
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DateFormat is the layout of the dates selecting a snapshot of the store
+const DateFormat = "2006-01-02"
+
+// Snapshot is a snapshot of the local store or an archive, opened to be
+// read back. Only its manifest is held in memory, the entity files are
+// streamed by Each.
+type Snapshot struct {
+	Path     string
+	Manifest Manifest
+}
+
+// Open reads the manifest of a snapshot, given as the path of a snapshot
+// directory or archive, the name of a snapshot of the store, or a date
+// (YYYY-MM-DD, UTC) selecting the last snapshot of the store taken that
+// day
+func Open(ref string) (*Snapshot, error) {
+	p, err := Resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Snapshot{Path: p}
+
+	err = s.open(ManifestFile, func(r io.Reader) error {
+		return json.NewDecoder(r).Decode(&s.Manifest)
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("'%s' is not a snapshot: %s is missing", p, ManifestFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s in '%s': %w", ManifestFile, p, err)
+	}
+
+	return s, nil
+}
+
+// Resolve returns the path of the snapshot a reference of Open stands for
+func Resolve(ref string) (string, error) {
+	if _, err := time.Parse(DateFormat, ref); err == nil {
+		return Latest(ref)
+	}
+
+	for _, p := range []string{ref, filepath.Join(Dir, ref), filepath.Join(Dir, ref) + ArchiveExt} {
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
+	}
+
+	return "", fmt.Errorf("snapshot '%s' not found", ref)
+}
+
+// Latest returns the path of the last snapshot of the store taken on a
+// date (YYYY-MM-DD, UTC)
+func Latest(date string) (string, error) {
+	day, err := time.Parse(DateFormat, date)
+	if err != nil {
+		return "", err
+	}
+
+	entries, err := os.ReadDir(Dir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+
+	dayPrefix := Prefix + day.Format("20060102") + "T"
+
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, dayPrefix) {
+			continue
+		}
+
+		// Skip the temporary directories of snapshots being taken
+		if (entry.IsDir() && len(name) == len(Prefix+NameFormat)) || strings.HasSuffix(name, ArchiveExt) {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		return "", fmt.Errorf("no snapshot taken on %s in '%s'", date, Dir)
+	}
+
+	// Names sort by time, a directory before the archive of the same time
+	sort.Strings(names)
+	return filepath.Join(Dir, names[len(names)-1]), nil
+}
+
+// Name returns the name of the snapshot, without the archive extension
+func (s *Snapshot) Name() string {
+	return strings.TrimSuffix(filepath.Base(s.Path), ArchiveExt)
+}
+
+// Each calls fn with every entity of a type, in the order they were
+// listed, reading its file one line at a time. The file is checked
+// against the checksum of the manifest as it is read: a mismatch is
+// reported once the file is read, and the entities must then be
+// discarded. It fails when the snapshot does not hold the type or could
+// not read it.
+func (s *Snapshot) Each(name string, fn func(json.RawMessage) error) error {
+	var entity *Entity
+	for i := range s.Manifest.Entities {
+		if s.Manifest.Entities[i].Name == name {
+			entity = &s.Manifest.Entities[i]
+		}
+	}
+
+	switch {
+	case entity == nil:
+		return fmt.Errorf("snapshot '%s' does not hold %s", s.Name(), name)
+	case entity.Error != "":
+		return fmt.Errorf("%w: %s could not be read in '%s': %s", ErrIncomplete, name, s.Name(), entity.Error)
+	}
+
+	return s.open(entity.File, func(r io.Reader) error {
+		sum := sha256.New()
+		scanner := bufio.NewScanner(io.TeeReader(r, sum))
+		scanner.Buffer(nil, 64*1024*1024)
+
+		for scanner.Scan() {
+			if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+				continue
+			}
+			if err := fn(scanner.Bytes()); err != nil {
+				return err
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+
+		if hexSum(sum) != entity.SHA256 {
+			return fmt.Errorf("%s of snapshot '%s' does not match its checksum", entity.File, s.Name())
+		}
+		return nil
+	})
+}
+
+// open calls fn with a reader of a file of the snapshot, from its
+// directory or its archive
+func (s *Snapshot) open(file string, fn func(io.Reader) error) error {
+	if !strings.HasSuffix(s.Path, ArchiveExt) {
+		f, err := os.Open(filepath.Join(s.Path, file))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		return fn(f)
+	}
+
+	f, err := os.Open(s.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("'%s' is not a snapshot archive: %w", s.Path, err)
+	}
+
+	// Files are found by scanning the archive, which is decompressed as
+	// it is read
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("%s in '%s': %w", file, s.Path, os.ErrNotExist)
+		}
+		if err != nil {
+			return fmt.Errorf("'%s' is not a snapshot archive: %w", s.Path, err)
+		}
+
+		if header.Typeflag == tar.TypeReg && path.Base(header.Name) == file {
+			return fn(tr)
+		}
+	}
+}
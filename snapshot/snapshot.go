@@ -0,0 +1,197 @@
+// Package snapshot captures every entity list of the 1Source REST API at
+// one point in time, for audit and debugging.
+//
+// A snapshot is a directory named after its UTC time, holding one NDJSON
+// file per entity type and a manifest of their counts, timings and
+// SHA-256 checksums. It can be packed into a .tar.gz archive, and two
+// snapshots can be compared entity by entity.
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Dir is the local store, the directory holding the snapshots
+var Dir = ".1source-snapshots"
+
+// ManifestFile is the name of the manifest in a snapshot
+const ManifestFile = "manifest.json"
+
+// NameFormat is the time layout of snapshot names, as in
+// snapshot-20240102T150405Z
+const NameFormat = "20060102T150405Z"
+
+// Prefix starts the name of every snapshot
+const Prefix = "snapshot-"
+
+// Manifest describes a snapshot
+type Manifest struct {
+	CreatedAt string   `json:"createdAt"`
+	Profile   string   `json:"profile,omitempty"`
+	ElapsedMs int64    `json:"elapsedMs"`
+	Entities  []Entity `json:"entities"`
+}
+
+// Entity describes the file of one entity type in a snapshot
+type Entity struct {
+	Name      string `json:"name"`
+	File      string `json:"file"`
+	Count     int    `json:"count"`
+	Bytes     int64  `json:"bytes"`
+	SHA256    string `json:"sha256"`
+	ElapsedMs int64  `json:"elapsedMs"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Fetch reads the list of an entity type, calling fn with each entity,
+// and returns the number of entities read
+type Fetch func(entity string, fn func(json.RawMessage) error) (int, error)
+
+// ErrIncomplete is returned by Take when an entity type could not be read
+var ErrIncomplete = errors.New("snapshot is incomplete")
+
+// Name returns the name of a snapshot taken at t
+func Name(t time.Time) string {
+	return Prefix + t.UTC().Format(NameFormat)
+}
+
+// Take reads every entity type concurrently with fetch and writes them
+// to a new snapshot in Dir. It returns the path of the snapshot and its
+// manifest. When some entity types fail, the snapshot is still written,
+// the manifest records the errors and ErrIncomplete is returned.
+func Take(entities []string, fetch Fetch, profile string) (string, *Manifest, error) {
+	start := time.Now()
+	path := filepath.Join(Dir, Name(start))
+
+	for _, existing := range []string{path, path + ArchiveExt} {
+		if _, err := os.Stat(existing); err == nil {
+			return "", nil, fmt.Errorf("snapshot '%s' already exists", existing)
+		}
+	}
+
+	if err := os.MkdirAll(Dir, 0700); err != nil {
+		return "", nil, err
+	}
+
+	// Write to a temporary directory first so the store never holds a
+	// partial snapshot
+	tmp, err := os.MkdirTemp(Dir, Name(start)+".tmp-")
+	if err != nil {
+		return "", nil, err
+	}
+
+	manifest := &Manifest{
+		CreatedAt: start.UTC().Format(time.RFC3339),
+		Profile:   profile,
+		Entities:  make([]Entity, len(entities)),
+	}
+
+	var wg sync.WaitGroup
+	for i, name := range entities {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			manifest.Entities[i] = writeEntity(tmp, name, fetch)
+		}(i, name)
+	}
+	wg.Wait()
+
+	manifest.ElapsedMs = time.Since(start).Milliseconds()
+
+	if err := writeManifest(tmp, manifest); err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.RemoveAll(tmp)
+		return "", nil, err
+	}
+
+	var failed []error
+	for _, e := range manifest.Entities {
+		if e.Error != "" {
+			failed = append(failed, fmt.Errorf("%s: %s", e.Name, e.Error))
+		}
+	}
+	if len(failed) > 0 {
+		return path, manifest, fmt.Errorf("%w: %w", ErrIncomplete, errors.Join(failed...))
+	}
+
+	return path, manifest, nil
+}
+
+// writeEntity writes the list of an entity type as NDJSON, one compact
+// entity per line, hashing it as it is written
+func writeEntity(dir string, name string, fetch Fetch) Entity {
+	start := time.Now()
+	entity := Entity{Name: name, File: name + ".ndjson"}
+
+	f, err := os.OpenFile(filepath.Join(dir, entity.File), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		entity.Error = err.Error()
+		return entity
+	}
+
+	sum := sha256.New()
+	counter := &countingWriter{}
+	out := bufio.NewWriter(io.MultiWriter(f, sum, counter))
+	var line bytes.Buffer
+
+	entity.Count, err = fetch(name, func(e json.RawMessage) error {
+		line.Reset()
+		if err := json.Compact(&line, e); err != nil {
+			return err
+		}
+		line.WriteByte('\n')
+
+		_, err := out.Write(line.Bytes())
+		return err
+	})
+
+	err = errors.Join(err, out.Flush(), f.Sync(), f.Close())
+	if err != nil {
+		entity.Error = err.Error()
+	}
+
+	entity.Bytes = counter.n
+	entity.SHA256 = hexSum(sum)
+	entity.ElapsedMs = time.Since(start).Milliseconds()
+
+	return entity
+}
+
+// writeManifest writes the manifest of a snapshot
+func writeManifest(dir string, manifest *Manifest) error {
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, ManifestFile), append(b, '\n'), 0600)
+}
+
+// countingWriter counts the bytes written to it
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// hexSum returns the hash as hex
+func hexSum(h hash.Hash) string {
+	return hex.EncodeToString(h.Sum(nil))
+}
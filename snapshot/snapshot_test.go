@@ -0,0 +1,136 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeFetch serves n pretty printed entities for every entity type, and
+// fails for the entity types in failing
+func fakeFetch(n int, failing ...string) Fetch {
+	return func(entity string, fn func(json.RawMessage) error) (int, error) {
+		for _, f := range failing {
+			if f == entity {
+				return 0, errors.New("unavailable")
+			}
+		}
+
+		for i := 1; i <= n; i++ {
+			if err := fn(json.RawMessage(fmt.Sprintf("{\n  \"id\": %d,\n  \"type\": %q\n}", i, entity))); err != nil {
+				return i - 1, err
+			}
+		}
+		return n, nil
+	}
+}
+
+func TestTake(t *testing.T) {
+	Dir = t.TempDir()
+
+	path, manifest, err := Take([]string{"contracts", "events"}, fakeFetch(3), "prod")
+	if err != nil {
+		t.Fatalf("Take() failed: %v", err)
+	}
+
+	if manifest.Profile != "prod" || len(manifest.Entities) != 2 {
+		t.Fatalf("Take() manifest = %+v", manifest)
+	}
+
+	for _, e := range manifest.Entities {
+		b, err := os.ReadFile(filepath.Join(path, e.File))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sum := sha256.Sum256(b)
+		if e.Count != 3 || e.Bytes != int64(len(b)) || e.SHA256 != hex.EncodeToString(sum[:]) {
+			t.Errorf("manifest entity %+v does not match its file", e)
+		}
+	}
+
+	b, err := os.ReadFile(filepath.Join(path, "contracts.ndjson"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\"id\":1,\"type\":\"contracts\"}\n{\"id\":2,\"type\":\"contracts\"}\n{\"id\":3,\"type\":\"contracts\"}\n"
+	if string(b) != expected {
+		t.Errorf("contracts.ndjson = %q, expected %q", b, expected)
+	}
+
+	var written Manifest
+	b, err = os.ReadFile(filepath.Join(path, ManifestFile))
+	if err != nil || json.Unmarshal(b, &written) != nil || written.Entities[1].SHA256 != manifest.Entities[1].SHA256 {
+		t.Errorf("manifest file does not match the returned manifest: %s", b)
+	}
+}
+
+func TestTakeIncomplete(t *testing.T) {
+	Dir = t.TempDir()
+
+	path, manifest, err := Take([]string{"contracts", "recalls"}, fakeFetch(1, "recalls"), "")
+	if !errors.Is(err, ErrIncomplete) {
+		t.Fatalf("Take() error = %v, expected ErrIncomplete", err)
+	}
+
+	if _, statErr := os.Stat(path); statErr != nil {
+		t.Errorf("incomplete snapshot not written: %v", statErr)
+	}
+	if manifest.Entities[0].Error != "" || manifest.Entities[1].Error != "unavailable" {
+		t.Errorf("Take() manifest errors = %+v", manifest.Entities)
+	}
+}
+
+func TestArchive(t *testing.T) {
+	Dir = t.TempDir()
+
+	path, _, err := Take([]string{"buyins"}, fakeFetch(2), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := Archive(path)
+	if err != nil {
+		t.Fatalf("Archive() failed: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("snapshot directory not removed: %v", err)
+	}
+
+	f, err := os.Open(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, header.Name)
+	}
+
+	base := filepath.Base(path)
+	if len(names) != 2 || names[0] != base+"/buyins.ndjson" || names[1] != base+"/"+ManifestFile {
+		t.Errorf("archive holds %v", names)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dharm-kapadia/1source-go/api"
+	"github.com/dharm-kapadia/1source-go/models"
+	"github.com/dharm-kapadia/1source-go/snapshot"
+)
+
+// takeSnapshot reads every entity type in parallel over one token into a
+// new snapshot of the local store, packed into an archive when asked,
+// and prints its manifest
+func takeSnapshot(bearer string, archive bool) error {
+	// Every page is read, whatever --limit says
+	opts := api.PageOptions{Size: listOptions.Size, Prefetch: listOptions.Prefetch}
+
+	fetch := func(entity string, fn func(json.RawMessage) error) (int, error) {
+		endPoint, _ := appConfig.Endpoints.Endpoint(entity)
+		return api.StreamPages(endPoint, bearer, opts, fn)
+	}
+
+	path, manifest, err := snapshot.Take(models.EntityNames, fetch, appConfig.Profile)
+	if path == "" {
+		return err
+	}
+
+	printManifest(os.Stdout, manifest)
+
+	if archive {
+		var archiveErr error
+		if path, archiveErr = snapshot.Archive(path); archiveErr != nil {
+			return archiveErr
+		}
+	}
+
+	fmt.Printf("\nSnapshot written to '%s'\n", path)
+	return err
+}
+
+// printManifest writes a table of the entity files of a snapshot
+func printManifest(w io.Writer, manifest *snapshot.Manifest) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ENTITY\tCOUNT\tBYTES\tELAPSED\tSHA-256\tERROR")
+
+	for _, e := range manifest.Entities {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%s\n", e.Name, e.Count, e.Bytes,
+			time.Duration(e.ElapsedMs)*time.Millisecond, e.SHA256, e.Error)
+	}
+
+	tw.Flush()
+
+	fmt.Fprintf(w, "\nTaken at %s in %s\n", manifest.CreatedAt, time.Duration(manifest.ElapsedMs)*time.Millisecond)
+}
+
+// diffSnapshots compares two snapshots, given as paths, names or dates of
+// the local store, and prints the new, removed and changed entities with
+// their changed fields and summary counts, or writes them to csvFile
+func diffSnapshots(fromRef string, toRef string, csvFile string) error {
+	from, err := snapshot.Open(fromRef)
+	if err != nil {
+		return err
+	}
+	to, err := snapshot.Open(toRef)
+	if err != nil {
+		return err
+	}
+
+	diffs := snapshot.Compare(from, to, snapshot.DiffEntities)
+
+	fmt.Printf("Comparing '%s' taken at %s\n     with '%s' taken at %s\n\n",
+		from.Name(), from.Manifest.CreatedAt, to.Name(), to.Manifest.CreatedAt)
+
+	if csvFile != "" {
+		file, err := os.Create(csvFile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		if err := snapshot.WriteCSV(file, diffs); err != nil {
+			return err
+		}
+
+		fmt.Printf("Differences written to '%s'\n\n", csvFile)
+	} else {
+		printDiffs(os.Stdout, diffs)
+	}
+
+	printDiffSummary(os.Stdout, diffs)
+
+	var failed []error
+	for _, d := range diffs {
+		if d.Error != "" {
+			failed = append(failed, errors.New(d.Error))
+		}
+	}
+	return errors.Join(failed...)
+}
+
+// printDiffs writes the new, removed and changed entities of each type,
+// with the old and new values of the changed fields
+func printDiffs(w io.Writer, diffs []snapshot.EntityDiff) {
+	for _, d := range diffs {
+		if d.Error != "" || len(d.Added)+len(d.Removed)+len(d.Changed) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(w, "%s: %d new, %d removed, %d changed\n", d.Name, len(d.Added), len(d.Removed), len(d.Changed))
+
+		for _, id := range d.Added {
+			fmt.Fprintf(w, "  + %s\n", id)
+		}
+		for _, id := range d.Removed {
+			fmt.Fprintf(w, "  - %s\n", id)
+		}
+		for _, c := range d.Changed {
+			fmt.Fprintf(w, "  ~ %s\n", c.Id)
+			for _, change := range c.Changes {
+				fmt.Fprintf(w, "      %s: %s -> %s\n", change.Field, diffValue(change.Old), diffValue(change.New))
+			}
+		}
+
+		fmt.Fprintln(w)
+	}
+}
+
+// diffValue returns the JSON value of a field, or (none) when the field
+// is absent
+func diffValue(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
+
+// printDiffSummary writes a table of the counts of each entity type
+func printDiffSummary(w io.Writer, diffs []snapshot.EntityDiff) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ENTITY\tNEW\tREMOVED\tCHANGED\tUNCHANGED\tERROR")
+
+	for _, d := range diffs {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%s\n", d.Name, len(d.Added), len(d.Removed), len(d.Changed), d.Unchanged, d.Error)
+	}
+
+	tw.Flush()
+}
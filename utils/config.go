@@ -0,0 +1,371 @@
+// utils contains utility functions
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/dharm-kapadia/1source-go/logging"
+	models "github.com/dharm-kapadia/1source-go/models"
+	"github.com/dharm-kapadia/1source-go/redact"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// GrantTypes are the OAuth grant types supported for logging in
+var GrantTypes = []string{"password"}
+
+// AuthTypes are the authentication types supported by the 1Source REST API
+var AuthTypes = []string{"BEARER"}
+
+// ConfigError is one problem found in a configuration file. Key is the
+// TOML key path of the offending value, empty for file level problems.
+type ConfigError struct {
+	Key     string
+	Message string
+}
+
+func (e ConfigError) Error() string {
+	if e.Key == "" {
+		return e.Message
+	}
+
+	return e.Key + ": " + e.Message
+}
+
+// ConfigErrors is the list of problems found in a configuration file
+type ConfigErrors []ConfigError
+
+func (errs ConfigErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, e := range errs {
+		messages[i] = e.Error()
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+// add records a problem for key
+func (errs *ConfigErrors) add(key string, format string, args ...any) {
+	*errs = append(*errs, ConfigError{Key: key, Message: fmt.Sprintf(format, args...)})
+}
+
+// profileSchema and configSchema describe every key allowed in a
+// configuration file, to detect unknown keys
+type (
+	profileSchema struct {
+		Inherits string
+		models.AppConfig
+	}
+
+	configSchema struct {
+		models.AppConfig
+		Profiles map[string]profileSchema
+	}
+)
+
+// ReadTOML opens and reads in application configuration TOML file,
+// resolves the named profile (the default profile when empty) and its
+// secret references, and validates the result. Every problem found is
+// returned at once as ConfigErrors.
+func ReadTOML(filename string, profile string) (*models.AppConfig, error) {
+	appConfig, warnings, errs := loadConfig(filename, profile)
+
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: '%s' %s\n", filename, warning)
+	}
+
+	if len(errs) > 0 {
+		return appConfig, errs
+	}
+
+	return appConfig, nil
+}
+
+// CheckConfig validates a configuration file and the named profile. It
+// returns the warnings and the problems found.
+func CheckConfig(filename string, profile string) ([]string, ConfigErrors) {
+	_, warnings, errs := loadConfig(filename, profile)
+	return warnings, errs
+}
+
+// loadConfig reads, resolves and validates a configuration file
+func loadConfig(filename string, profile string) (*models.AppConfig, []string, ConfigErrors) {
+	var appConfig models.AppConfig
+	var errs ConfigErrors
+
+	b, err := os.ReadFile(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		errs.add("", "configuration TOML file '%s' does not exist", filename)
+		return &appConfig, nil, errs
+	}
+	if err != nil {
+		errs.add("", "cannot read configuration TOML file '%s': %s", filename, err)
+		return &appConfig, nil, errs
+	}
+
+	// Syntax errors stop the validation, unknown keys do not
+	if fatal := checkKeys(b, &errs); fatal {
+		return &appConfig, nil, errs
+	}
+
+	doc, err := resolveDocument(b, profile)
+	if err != nil {
+		errs.add("", "%s", err)
+		return &appConfig, nil, errs
+	}
+
+	if err := ResolveProfile(b, profile, &appConfig); err != nil {
+		errs.add("", "%s", err)
+		return &appConfig, nil, errs
+	}
+
+	for _, section := range []string{"general", "endpoints", "authentication"} {
+		if _, found := doc[section]; !found {
+			errs.add(section, "section is missing")
+		}
+	}
+
+	warnings, err := ResolveSecrets(&appConfig)
+	var secretErrs ConfigErrors
+	if errors.As(err, &secretErrs) {
+		errs = append(errs, secretErrs...)
+	} else if err != nil {
+		errs.add("", "%s", err)
+	}
+
+	redact.AddFields(appConfig.Redaction.Fields...)
+
+	appConfig.Endpoints.Derive()
+	checkValues(&appConfig, &errs)
+
+	// Values may come from any profile in the inheritance chain
+	if appConfig.Profile != DefaultProfile {
+		for i := range errs {
+			if errs[i].Key != "" {
+				errs[i].Key += fmt.Sprintf(" (profile '%s')", appConfig.Profile)
+			}
+		}
+	}
+
+	return &appConfig, warnings, errs
+}
+
+// checkKeys decodes the configuration strictly and records a problem for
+// every unknown key, with a suggestion when it looks like a typo. It
+// reports whether the document could not be parsed at all.
+func checkKeys(b []byte, errs *ConfigErrors) bool {
+	var schema configSchema
+
+	decoder := toml.NewDecoder(bytes.NewReader(b))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&schema)
+
+	var strictErr *toml.StrictMissingError
+	var decodeErr *toml.DecodeError
+
+	switch {
+	case err == nil:
+		return false
+
+	case errors.As(err, &strictErr):
+		for _, e := range strictErr.Errors {
+			path := e.Key()
+			row, _ := e.Position()
+
+			message := fmt.Sprintf("unknown key on line %d", row)
+			if suggestion := closest(path[len(path)-1], knownKeys(path[:len(path)-1])); suggestion != "" {
+				message += fmt.Sprintf(", did you mean '%s'?", suggestion)
+			}
+			errs.add(strings.Join(path, "."), "%s", message)
+		}
+		return false
+
+	case errors.As(err, &decodeErr):
+		row, column := decodeErr.Position()
+		errs.add(strings.Join(decodeErr.Key(), "."), "line %d, column %d: %s", row, column, decodeErr.Error())
+		return true
+	}
+
+	errs.add("", "%s", err)
+	return true
+}
+
+// knownKeys returns the keys allowed in the table at path
+func knownKeys(path []string) []string {
+	t := reflect.TypeOf(configSchema{})
+
+	for i := 0; i < len(path); i++ {
+		if t.Kind() == reflect.Map {
+			// Skip the profile name
+			t = t.Elem()
+			continue
+		}
+
+		field, found := findField(t, path[i])
+		if !found {
+			return nil
+		}
+		t = field.Type
+	}
+
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var keys []string
+	collectKeys(t, &keys)
+	sort.Strings(keys)
+
+	return keys
+}
+
+// findField finds the field of a struct type matching a TOML key,
+// looking into embedded structs
+func findField(t reflect.Type, key string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		if field.Anonymous {
+			if f, found := findField(field.Type, key); found {
+				return f, true
+			}
+			continue
+		}
+
+		if strings.EqualFold(field.Name, key) {
+			return field, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+// collectKeys appends the TOML keys of the fields of a struct type
+func collectKeys(t reflect.Type, keys *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		switch {
+		case field.Anonymous:
+			collectKeys(field.Type, keys)
+		case field.IsExported() && field.Tag.Get("toml") != "-":
+			*keys = append(*keys, strings.ToLower(field.Name))
+		}
+	}
+}
+
+// closest returns the known key nearest to key, when it is at most two
+// edits away
+func closest(key string, known []string) string {
+	best, bestDistance := "", 3
+
+	for _, k := range known {
+		if d := levenshtein(strings.ToLower(key), k); d < bestDistance {
+			best, bestDistance = k, d
+		}
+	}
+
+	return best
+}
+
+// levenshtein returns the edit distance between two strings
+func levenshtein(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+// checkValues validates the resolved configuration values
+func checkValues(appConfig *models.AppConfig, errs *ConfigErrors) {
+	general := appConfig.General
+	checkURL(errs, "general.auth_url", general.Auth_URL, true)
+	if general.Realm_Name == "" {
+		errs.add("general.realm_name", "is required")
+	}
+
+	endpoints := appConfig.Endpoints
+	checkURL(errs, "endpoints.base", endpoints.Base, false)
+	for _, entity := range models.EntityNames {
+		endPoint, _ := endpoints.Endpoint(entity)
+		if endPoint == "" {
+			errs.add("endpoints."+entity, "is not set and cannot be derived, set endpoints.base")
+			continue
+		}
+		checkURL(errs, "endpoints."+entity, endPoint, true)
+	}
+
+	checkOneOf(errs, "logging.level", appConfig.Logging.Level, logging.Levels)
+	checkOneOf(errs, "logging.format", appConfig.Logging.Format, logging.Formats)
+
+	auth := appConfig.Authentication
+	checkOneOf(errs, "authentication.auth_type", auth.Auth_Type, AuthTypes)
+	checkOneOf(errs, "authentication.grant_type", auth.Grant_Type, GrantTypes)
+
+	for key, value := range map[string]string{
+		"authentication.client_id": auth.Client_Id,
+		"authentication.username":  auth.Username,
+		"authentication.password":  auth.Password,
+	} {
+		if strings.TrimSpace(value) == "" {
+			errs.add(key, "is empty")
+		}
+	}
+
+	// Map iteration order is random, keep the report stable
+	sort.SliceStable(*errs, func(i, j int) bool {
+		return (*errs)[i].Key < (*errs)[j].Key
+	})
+}
+
+// checkURL checks that value is an absolute http or https URL
+func checkURL(errs *ConfigErrors, key string, value string, required bool) {
+	if value == "" {
+		if required {
+			errs.add(key, "is required")
+		}
+		return
+	}
+
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs.add(key, "'%s' is not a valid http or https URL", value)
+	}
+}
+
+// checkOneOf checks that a value, when set, is one of the allowed values
+func checkOneOf(errs *ConfigErrors, key string, value string, allowed []string) {
+	if value == "" {
+		return
+	}
+
+	for _, a := range allowed {
+		if strings.EqualFold(value, a) {
+			return
+		}
+	}
+
+	errs.add(key, "unknown value '%s', expected one of %s", value, strings.Join(allowed, ", "))
+}
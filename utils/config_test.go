@@ -0,0 +1,184 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/dharm-kapadia/1source-go/redact"
+)
+
+const validTOML = `
+[general]
+auth_url = 'https://stageauth.equilend.com/auth'
+realm_name = '1Source'
+
+[endpoints]
+base = 'https://stageapi.equilend.com'
+api_version = 'v1'
+
+[authentication]
+auth_type = 'BEARER'
+grant_type = 'password'
+client_id = 'canton-participant1-client'
+username = 'TestLender1User'
+password = '${ONESOURCE_TEST_PASSWORD}'
+`
+
+// writeConfig writes a configuration file and returns its path
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "configuration.toml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// keys returns the keys of the problems found, "" for file level ones
+func keys(errs ConfigErrors) []string {
+	var keys []string
+	for _, e := range errs {
+		keys = append(keys, e.Key)
+	}
+	return keys
+}
+
+func TestCheckConfigValid(t *testing.T) {
+	defer redact.Reset()
+	t.Setenv("ONESOURCE_TEST_PASSWORD", "password")
+
+	warnings, errs := CheckConfig(writeConfig(t, validTOML), "")
+	if len(warnings) != 0 || len(errs) != 0 {
+		t.Errorf("CheckConfig() = %q, %v, expected no warnings nor problems", warnings, errs)
+	}
+}
+
+func TestCheckConfigUnknownKeys(t *testing.T) {
+	defer redact.Reset()
+	t.Setenv("ONESOURCE_TEST_PASSWORD", "password")
+
+	doc := strings.Replace(validTOML, "username =", "usernme =", 1) +
+		"\n[profiles.prod.general]\nauth_ur = 'https://auth.example.com'\nregion = 'eu'\n"
+
+	_, errs := CheckConfig(writeConfig(t, doc), "")
+
+	expected := map[string]string{
+		"authentication.usernme":        "did you mean 'username'?",
+		"profiles.prod.general.auth_ur": "did you mean 'auth_url'?",
+		"profiles.prod.general.region":  "unknown key on line",
+	}
+	for key, message := range expected {
+		found := false
+		for _, e := range errs {
+			if e.Key == key && strings.Contains(e.Message, message) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("CheckConfig() = %v, expected %s: %s", errs, key, message)
+		}
+	}
+
+	for _, e := range errs {
+		if e.Key == "profiles.prod.general.region" && strings.Contains(e.Message, "did you mean") {
+			t.Errorf("CheckConfig() suggests a key far from 'region': %s", e)
+		}
+	}
+}
+
+func TestCheckConfigSyntax(t *testing.T) {
+	_, errs := CheckConfig(writeConfig(t, "[general\nauth_url = 'x'\n"), "")
+	if len(errs) != 1 || !strings.Contains(errs[0].Message, "line 1") {
+		t.Errorf("CheckConfig() of a syntax error = %v, expected one problem on line 1", errs)
+	}
+}
+
+func TestCheckConfigMissingSections(t *testing.T) {
+	_, errs := CheckConfig(writeConfig(t, "[general]\nauth_url = 'https://auth.example.com'\nrealm_name = '1Source'\n"), "")
+
+	for _, section := range []string{"endpoints", "authentication"} {
+		found := false
+		for _, e := range errs {
+			if e.Key == section && e.Message == "section is missing" {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("CheckConfig() = %v, expected %s to be missing", errs, section)
+		}
+	}
+}
+
+func TestCheckConfigSecretFilePermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file permissions are not checked on Windows")
+	}
+	defer redact.Reset()
+
+	secret := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(secret, []byte("password\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(secret, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	doc := strings.Replace(validTOML, "${ONESOURCE_TEST_PASSWORD}", "${file:"+secret+"}", 1)
+	_, errs := CheckConfig(writeConfig(t, doc), "")
+	if len(errs) != 1 || errs[0].Key != "authentication.password" || !strings.Contains(errs[0].Message, "chmod 600") {
+		t.Errorf("CheckConfig() = %v, expected the secret file permissions to be refused", errs)
+	}
+
+	// Readable by its owner only, it is accepted
+	if err := os.Chmod(secret, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, errs := CheckConfig(writeConfig(t, doc), ""); len(errs) != 0 {
+		t.Errorf("CheckConfig() with a 0600 secret file = %v", errs)
+	}
+}
+
+func TestReadTOMLReportsEveryProblem(t *testing.T) {
+	defer redact.Reset()
+	os.Unsetenv("ONESOURCE_TEST_PASSWORD")
+
+	doc := strings.NewReplacer(
+		"auth_url = 'https://stageauth.equilend.com/auth'", "auth_url = 'stageauth'",
+		"grant_type = 'password'", "grant_type = 'implicit'",
+		"username = 'TestLender1User'", "username = ''",
+	).Replace(validTOML)
+
+	// Every problem is returned at once as ConfigErrors, which the
+	// command line maps to its configuration exit code
+	_, err := ReadTOML(writeConfig(t, doc), "")
+
+	var errs ConfigErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("ReadTOML() error = %v, expected ConfigErrors", err)
+	}
+
+	expected := []string{"authentication.grant_type", "authentication.password", "authentication.username", "general.auth_url"}
+	if got := keys(errs); strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Errorf("ReadTOML() problems in %v, expected %v", got, expected)
+	}
+
+	// A named profile is named in the keys of its problems
+	_, errs = CheckConfig(writeConfig(t, doc+"\n[profiles.prod.authentication]\nusername = 'ProdUser'\n"), "prod")
+	if len(errs) != 3 {
+		t.Errorf("CheckConfig() of profile prod = %v, expected 3 problems", errs)
+	}
+	for _, e := range errs {
+		if !strings.HasSuffix(e.Key, "(profile 'prod')") {
+			t.Errorf("problem of profile prod = %s, expected the profile in its key", e)
+		}
+	}
+
+	if _, err := ReadTOML(filepath.Join(t.TempDir(), "missing.toml"), ""); !errors.As(err, &errs) {
+		t.Errorf("ReadTOML() of a missing file error = %v, expected ConfigErrors", err)
+	}
+}
@@ -0,0 +1,211 @@
+// utils contains utility functions
+package utils
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	models "github.com/dharm-kapadia/1source-go/models"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// DefaultProfile names the profile made of the top-level general,
+// endpoints and authentication sections of the configuration file
+const DefaultProfile = "default"
+
+// ProfileInfo describes a profile of the configuration file
+type ProfileInfo struct {
+	Name     string
+	Inherits string
+	AuthURL  string
+	Username string
+}
+
+// ResolveProfile decodes the named profile of a configuration TOML
+// document into appConfig. Named profiles live under [profiles.<name>],
+// with their own general, endpoints and authentication sections, and
+// inherit every value they do not set from the profile named by their
+// "inherits" key, the default profile when not set.
+func ResolveProfile(b []byte, profile string, appConfig *models.AppConfig) error {
+	merged, err := resolveDocument(b, profile)
+	if err != nil {
+		return err
+	}
+
+	if profile == "" {
+		profile = DefaultProfile
+	}
+
+	resolved, err := toml.Marshal(merged)
+	if err != nil {
+		return err
+	}
+
+	if err := toml.Unmarshal(resolved, appConfig); err != nil {
+		return err
+	}
+
+	appConfig.Profile = profile
+
+	return nil
+}
+
+// resolveDocument returns the sections of the named profile of a
+// configuration TOML document, merged over the profiles it inherits from
+func resolveDocument(b []byte, profile string) (map[string]any, error) {
+	var doc map[string]any
+	if err := toml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+
+	if profile == "" {
+		profile = DefaultProfile
+	}
+
+	return resolve(doc, profile, nil)
+}
+
+// resolve returns the sections of a profile merged over the sections of
+// the profiles it inherits from. seen holds the profiles already visited
+// to detect inheritance cycles.
+func resolve(doc map[string]any, profile string, seen []string) (map[string]any, error) {
+	for _, s := range seen {
+		if s == profile {
+			return nil, fmt.Errorf("profile inheritance cycle: %s -> %s", strings.Join(seen, " -> "), profile)
+		}
+	}
+	seen = append(seen, profile)
+
+	if profile == DefaultProfile {
+		if _, found := profiles(doc)[DefaultProfile]; !found {
+			base := make(map[string]any)
+			for key, value := range doc {
+				if key != "profiles" {
+					base[key] = value
+				}
+			}
+			return base, nil
+		}
+	}
+
+	sections, found := profiles(doc)[profile]
+	if !found {
+		return nil, fmt.Errorf("profile '%s' is not defined in the configuration file", profile)
+	}
+
+	parent := DefaultProfile
+	if inherits, ok := sections["inherits"].(string); ok && inherits != "" {
+		parent = inherits
+	}
+
+	var merged map[string]any
+	if parent == profile {
+		// [profiles.default] extends the top-level sections
+		merged = make(map[string]any)
+		for key, value := range doc {
+			if key != "profiles" {
+				merged[key] = value
+			}
+		}
+	} else {
+		var err error
+		if merged, err = resolve(doc, parent, seen); err != nil {
+			return nil, err
+		}
+	}
+
+	overlay := make(map[string]any)
+	for key, value := range sections {
+		if key != "inherits" {
+			overlay[key] = value
+		}
+	}
+
+	return merge(merged, overlay), nil
+}
+
+// profiles returns the [profiles.<name>] tables of a configuration document
+func profiles(doc map[string]any) map[string]map[string]any {
+	result := make(map[string]map[string]any)
+
+	tables, _ := doc["profiles"].(map[string]any)
+	for name, table := range tables {
+		if sections, ok := table.(map[string]any); ok {
+			result[name] = sections
+		}
+	}
+
+	return result
+}
+
+// merge returns a copy of base with the values of overlay laid over it,
+// merging nested tables key by key
+func merge(base map[string]any, overlay map[string]any) map[string]any {
+	result := make(map[string]any, len(base))
+	for key, value := range base {
+		result[key] = value
+	}
+
+	for key, value := range overlay {
+		baseTable, baseOk := result[key].(map[string]any)
+		overlayTable, overlayOk := value.(map[string]any)
+
+		if baseOk && overlayOk {
+			result[key] = merge(baseTable, overlayTable)
+		} else {
+			result[key] = value
+		}
+	}
+
+	return result
+}
+
+// ListProfiles returns the profiles defined in a configuration file,
+// starting with the default profile, each resolved with its inherited
+// values. Secret references are left unresolved.
+func ListProfiles(filename string) ([]ProfileInfo, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[string]any
+	if err := toml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+
+	names := []string{DefaultProfile}
+	for name := range profiles(doc) {
+		if name != DefaultProfile {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names[1:])
+
+	var infos []ProfileInfo
+	for _, name := range names {
+		var appConfig models.AppConfig
+		if err := ResolveProfile(b, name, &appConfig); err != nil {
+			return nil, err
+		}
+
+		info := ProfileInfo{
+			Name:     name,
+			AuthURL:  appConfig.General.Auth_URL,
+			Username: appConfig.Authentication.Username,
+		}
+
+		if sections, found := profiles(doc)[name]; found {
+			info.Inherits = DefaultProfile
+			if inherits, ok := sections["inherits"].(string); ok && inherits != "" && inherits != name {
+				info.Inherits = inherits
+			}
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
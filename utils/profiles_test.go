@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	models "github.com/dharm-kapadia/1source-go/models"
+)
+
+const profilesTOML = `
+[general]
+auth_url = 'https://auth.uat.example.com'
+realm_name = '1Source'
+
+[authentication]
+username = 'UatLender'
+password = '${ONESOURCE_PASSWORD}'
+
+[profiles.borrower.authentication]
+username = 'UatBorrower'
+
+[profiles.prod]
+inherits = 'borrower'
+
+[profiles.prod.general]
+auth_url = 'https://auth.example.com'
+
+[profiles.loop1]
+inherits = 'loop2'
+
+[profiles.loop2]
+inherits = 'loop1'
+`
+
+func TestResolveProfile(t *testing.T) {
+	tests := []struct {
+		profile  string
+		authURL  string
+		username string
+	}{
+		{"", "https://auth.uat.example.com", "UatLender"},
+		{DefaultProfile, "https://auth.uat.example.com", "UatLender"},
+		{"borrower", "https://auth.uat.example.com", "UatBorrower"},
+		// Values are inherited along the chain prod -> borrower -> default
+		{"prod", "https://auth.example.com", "UatBorrower"},
+	}
+
+	for _, tt := range tests {
+		var appConfig models.AppConfig
+		if err := ResolveProfile([]byte(profilesTOML), tt.profile, &appConfig); err != nil {
+			t.Fatalf("ResolveProfile(%q) error = %v", tt.profile, err)
+		}
+
+		if appConfig.General.Auth_URL != tt.authURL || appConfig.Authentication.Username != tt.username ||
+			appConfig.General.Realm_Name != "1Source" {
+			t.Errorf("ResolveProfile(%q) = %+v %+v", tt.profile, appConfig.General, appConfig.Authentication)
+		}
+	}
+}
+
+func TestResolveProfileDefaultSection(t *testing.T) {
+	// [profiles.default] extends the top-level sections
+	doc := profilesTOML + "\n[profiles.default.authentication]\nusername = 'DefaultUser'\n"
+
+	var appConfig models.AppConfig
+	if err := ResolveProfile([]byte(doc), "", &appConfig); err != nil {
+		t.Fatal(err)
+	}
+	if appConfig.Authentication.Username != "DefaultUser" || appConfig.General.Realm_Name != "1Source" {
+		t.Errorf("ResolveProfile() = %+v %+v", appConfig.General, appConfig.Authentication)
+	}
+}
+
+func TestResolveProfileErrors(t *testing.T) {
+	tests := []struct {
+		profile string
+		message string
+	}{
+		{"loop1", "profile inheritance cycle: loop1 -> loop2 -> loop1"},
+		{"staging", "profile 'staging' is not defined"},
+	}
+
+	for _, tt := range tests {
+		var appConfig models.AppConfig
+		err := ResolveProfile([]byte(profilesTOML), tt.profile, &appConfig)
+		if err == nil || !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("ResolveProfile(%q) error = %v, expected %q", tt.profile, err, tt.message)
+		}
+	}
+}
+
+func TestListProfiles(t *testing.T) {
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "helper-ran")
+
+	// Listing must neither run credential helpers nor need the variables
+	// the secrets reference
+	doc := strings.Replace(profilesTOML, "password = '${ONESOURCE_PASSWORD}'", "password = '${exec:touch "+marker+"}'", 1)
+	doc = strings.Replace(doc, "[profiles.loop1]\ninherits = 'loop2'\n\n[profiles.loop2]\ninherits = 'loop1'\n", "", 1)
+
+	file := filepath.Join(dir, "configuration.toml")
+	if err := os.WriteFile(file, []byte(doc), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	infos, err := ListProfiles(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []ProfileInfo{
+		{Name: DefaultProfile, AuthURL: "https://auth.uat.example.com", Username: "UatLender"},
+		{Name: "borrower", Inherits: DefaultProfile, AuthURL: "https://auth.uat.example.com", Username: "UatBorrower"},
+		{Name: "prod", Inherits: "borrower", AuthURL: "https://auth.example.com", Username: "UatBorrower"},
+	}
+	if !reflect.DeepEqual(infos, expected) {
+		t.Errorf("ListProfiles() = %+v, expected %+v", infos, expected)
+	}
+
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("ListProfiles() ran a credential helper")
+	}
+}
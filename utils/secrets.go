@@ -0,0 +1,183 @@
+// utils contains utility functions
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+
+	models "github.com/dharm-kapadia/1source-go/models"
+	"github.com/dharm-kapadia/1source-go/redact"
+)
+
+// SecretHelperTimeout bounds the run time of a credential helper command
+var SecretHelperTimeout = 10 * time.Second
+
+// sensitiveKeys are the configuration keys which must not be stored in
+// plain text
+var sensitiveKeys = map[string]bool{
+	"authentication.password":      true,
+	"authentication.client_secret": true,
+}
+
+// ResolveSecrets replaces the string values of the configuration which
+// are entirely a reference by the secret they reference:
+//
+//	${VAR} or ${env:VAR}   the value of an environment variable
+//	${file:/path}          the content of a file readable only by its owner
+//	${exec:command args}   the output of a credential helper command
+//
+// Any other value, such as a plain text password holding "$", "$$" or
+// "${", is taken literally. It returns a warning for every sensitive
+// value stored in plain text, and ConfigErrors listing every reference
+// that could not be resolved.
+func ResolveSecrets(appConfig *models.AppConfig) ([]string, error) {
+	var warnings []string
+	var errs ConfigErrors
+
+	err := walkStrings(reflect.ValueOf(appConfig).Elem(), "", func(key string, value string) (string, error) {
+		ref, isRef := reference(value)
+		if !isRef {
+			if sensitiveKeys[key] && value != "" {
+				warnings = append(warnings, fmt.Sprintf("%s is stored in plain text, use ${ENV_VAR}, ${file:path} or ${exec:command} instead", key))
+			}
+			return value, nil
+		}
+
+		resolved, err := lookup(ref)
+		if err != nil {
+			errs = append(errs, ConfigError{Key: key, Message: err.Error()})
+			return value, nil
+		}
+
+		// Credentials must never be printed once resolved
+		if sensitiveKeys[key] {
+			redact.AddSecrets(resolved)
+		}
+
+		return resolved, nil
+	})
+
+	if err == nil && len(errs) > 0 {
+		err = errs
+	}
+
+	return warnings, err
+}
+
+// walkStrings calls fn for every exported string field of a struct value,
+// recursively, with the TOML style key path of the field, and stores the
+// returned value in the field
+func walkStrings(v reflect.Value, prefix string, fn func(key string, value string) (string, error)) error {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || field.Tag.Get("toml") == "-" {
+			continue
+		}
+
+		key := strings.ToLower(field.Name)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		switch field.Type.Kind() {
+		case reflect.Struct:
+			if err := walkStrings(v.Field(i), key, fn); err != nil {
+				return err
+			}
+		case reflect.String:
+			value, err := fn(key, v.Field(i).String())
+			if err != nil {
+				return err
+			}
+			v.Field(i).SetString(value)
+		}
+	}
+
+	return nil
+}
+
+// reference returns the reference of a value which is entirely a secret
+// reference, "${ref}"
+func reference(value string) (string, bool) {
+	if len(value) < 4 || !strings.HasPrefix(value, "${") || !strings.HasSuffix(value, "}") {
+		return "", false
+	}
+
+	return value[2 : len(value)-1], true
+}
+
+// lookup returns the content of one secret reference
+func lookup(ref string) (string, error) {
+	kind, arg, found := strings.Cut(ref, ":")
+	if !found {
+		kind, arg = "env", ref
+	}
+
+	switch kind {
+	case "env":
+		secret, ok := os.LookupEnv(arg)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", arg)
+		}
+		return secret, nil
+
+	case "file":
+		return readSecretFile(arg)
+
+	case "exec":
+		return runSecretHelper(arg)
+	}
+
+	return "", fmt.Errorf("unknown secret reference '${%s}'", ref)
+}
+
+// readSecretFile reads a secret from a file which must not be accessible
+// by group or others
+func readSecretFile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		return "", fmt.Errorf("secret file '%s' has permissions %s, it must not be accessible by group or others (chmod 600)",
+			path, info.Mode().Perm())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// runSecretHelper runs a credential helper command, without a shell, and
+// returns what it prints on standard output
+func runSecretHelper(command string) (string, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return "", fmt.Errorf("empty credential helper command")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), SecretHelperTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stderr = os.Stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("credential helper '%s' failed: %w", args[0], err)
+	}
+
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	models "github.com/dharm-kapadia/1source-go/models"
+	"github.com/dharm-kapadia/1source-go/redact"
+)
+
+// secretFile writes a secret file with the given permissions
+func secretFile(t *testing.T, content string, perm os.FileMode) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(path, []byte(content), perm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, perm); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestResolveSecrets(t *testing.T) {
+	defer redact.Reset()
+	t.Setenv("ONESOURCE_TEST_SECRET", "from-env")
+
+	file := secretFile(t, "from-file\n", 0600)
+
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"${ONESOURCE_TEST_SECRET}", "from-env"},
+		{"${env:ONESOURCE_TEST_SECRET}", "from-env"},
+		{"${file:" + file + "}", "from-file"},
+
+		// Values which are not entirely a reference are taken literally
+		{"pa${ONESOURCE_TEST_SECRET}ss", "pa${ONESOURCE_TEST_SECRET}ss"},
+		{"${ONESOURCE_TEST_SECRET}x", "${ONESOURCE_TEST_SECRET}x"},
+		{"pa$$word", "pa$$word"},
+		{"pa$word", "pa$word"},
+		{"${ONESOURCE_TEST_SECRET", "${ONESOURCE_TEST_SECRET"},
+		{"${}", "${}"},
+		{"", ""},
+	}
+	if runtime.GOOS != "windows" {
+		tests = append(tests, struct {
+			value    string
+			expected string
+		}{"${exec:echo from-helper}", "from-helper"})
+	}
+
+	for _, tt := range tests {
+		var appConfig models.AppConfig
+		appConfig.General.Auth_URL = tt.value
+
+		if _, err := ResolveSecrets(&appConfig); err != nil || appConfig.General.Auth_URL != tt.expected {
+			t.Errorf("ResolveSecrets(%q) = %q, %v, expected %q", tt.value, appConfig.General.Auth_URL, err, tt.expected)
+		}
+	}
+}
+
+func TestResolveSecretsErrors(t *testing.T) {
+	defer redact.Reset()
+	os.Unsetenv("ONESOURCE_TEST_UNSET")
+
+	tests := []struct {
+		value   string
+		message string
+	}{
+		{"${ONESOURCE_TEST_UNSET}", "ONESOURCE_TEST_UNSET is not set"},
+		{"${vault:secret/1source}", "unknown secret reference"},
+		{"${file:" + filepath.Join(t.TempDir(), "missing") + "}", "no such file"},
+		{"${exec:}", "empty credential helper command"},
+	}
+	if runtime.GOOS != "windows" {
+		tests = append(tests, struct {
+			value   string
+			message string
+		}{"${file:" + secretFile(t, "x", 0644) + "}", "must not be accessible by group or others"})
+	}
+
+	for _, tt := range tests {
+		var appConfig models.AppConfig
+		appConfig.Authentication.Password = tt.value
+
+		_, err := ResolveSecrets(&appConfig)
+
+		var errs ConfigErrors
+		if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Key != "authentication.password" ||
+			!strings.Contains(errs[0].Message, tt.message) {
+			t.Errorf("ResolveSecrets(%q) error = %v, expected %q", tt.value, err, tt.message)
+		}
+
+		// An unresolved reference is left as it is
+		if appConfig.Authentication.Password != tt.value {
+			t.Errorf("ResolveSecrets(%q) set the password to %q", tt.value, appConfig.Authentication.Password)
+		}
+	}
+}
+
+func TestResolveSecretsWarnings(t *testing.T) {
+	defer redact.Reset()
+	t.Setenv("ONESOURCE_TEST_SECRET", "from-env")
+
+	var appConfig models.AppConfig
+	appConfig.Authentication.Password = "pa$$word"
+	appConfig.Authentication.Client_Secret = "${ONESOURCE_TEST_SECRET}"
+
+	warnings, err := ResolveSecrets(&appConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], "authentication.password is stored in plain text") {
+		t.Errorf("ResolveSecrets() warnings = %q, expected one for the plain text password", warnings)
+	}
+
+	// Resolved credentials are never printed
+	if out := redact.String("secret=from-env"); strings.Contains(out, "from-env") {
+		t.Errorf("resolved client secret is not redacted: %s", out)
+	}
+}
@@ -3,13 +3,9 @@ package utils
 
 import (
 	"fmt"
-	"io"
 	"log"
 	"os"
 	"strings"
-
-	models "github.com/dharm-kapadia/1source-go/models"
-	"github.com/pelletier/go-toml/v2"
 )
 
 // FileExists checks that the specified file exists
@@ -26,45 +22,48 @@ func FileExists(filename string) bool {
 	return !info.IsDir()
 }
 
-// ReadTOML opens and reads in application configuration TOML file
-func ReadTOML(filename string) (*models.AppConfig, error) {
-	var appConfig models.AppConfig
-	var err error
-	var file *os.File
-
-	if FileExists(filename) {
-		// TOML file exists  ... open it
-		file, err = os.Open(filename)
-
-		if err != nil {
-			log.Panic(err)
-			panic(err)
-		}
+// ExtractFlag reports whether the boolean flag is present in args and
+// returns args with every occurrence of the flag removed
+func ExtractFlag(args []string, flag string) (bool, []string) {
+	found := false
+	rest := make([]string, 0, len(args))
 
-		defer file.Close()
-
-		// Read file contents
-		b, err := io.ReadAll(file)
-
-		if err != nil {
-			log.Panic(err)
-			panic(err)
+	for _, arg := range args {
+		if arg == flag {
+			found = true
+		} else {
+			rest = append(rest, arg)
 		}
+	}
 
-		// Unmarshall it from TOML to defined struct
-		err = toml.Unmarshal(b, &appConfig)
+	return found, rest
+}
 
-		if err != nil {
-			log.Panic(err)
-			panic(err)
-		} else {
-			log.Printf("Successfully read and parsed '%s'\n", filename)
+// ExtractOption returns the value of the option given either as
+// "--name value" or "--name=value" in args, whether it was found, and
+// args with the option removed. The last occurrence wins.
+func ExtractOption(args []string, name string) (string, bool, []string) {
+	var value string
+	found := false
+	rest := make([]string, 0, len(args))
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		switch {
+		case arg == name && i+1 < len(args):
+			value = args[i+1]
+			found = true
+			i++
+		case strings.HasPrefix(arg, name+"="):
+			value = strings.TrimPrefix(arg, name+"=")
+			found = true
+		default:
+			rest = append(rest, arg)
 		}
-	} else {
-		fmt.Printf("Configuration TOML file '%s' does not exists\n", filename)
 	}
 
-	return &appConfig, err
+	return value, found, rest
 }
 
 // DisplayVersion prints the program version
@@ -75,11 +74,18 @@ func DisplayVersion() {
 // DisplayHelp creates the complete help string output for the command line
 func DisplayHelp() {
 	fmt.Print("Usage: 1Source [--help] [--version] -t VAR\n")
-	fmt.Print("Note: -t is required\n\n")
+	fmt.Print("       1Source validate JSON\n")
+	fmt.Print("       1Source check-ids JSON\n")
+	fmt.Print("       1Source collateral [--fill] JSON\n")
+	fmt.Print("       1Source [--csv FILE] audit verify|export\n")
+	fmt.Print("       1Source simulate ADDRESS\n")
+	fmt.Print("       1Source [--csv FILE] diff SNAPSHOT SNAPSHOT\n")
+	fmt.Print("Note: -t is required, except for offline commands\n\n")
 	fmt.Println("Optional arguments:")
 	fmt.Println("-h, --help\tshows help message and exits")
 	fmt.Print("-v, --version\tprints version information and exits\n\n")
 	fmt.Println("-t\t\t1Source configuration TOML file [required]")
+	fmt.Println("--profile\tnamed profile of the configuration TOML file to use [default: top-level sections]")
 	fmt.Println("-g\t\t1Source API Endpoint to query [agreements, contracts, events, parties, returns, rerates, recalls, buyins]")
 
 	fmt.Println("-a\t\t1Source API Endpoint to query trade agreements by agreement_id")
@@ -88,17 +94,76 @@ func DisplayHelp() {
 	fmt.Println("-ch\t\t1Source API Endpoint to get contract history by contract_id")
 	fmt.Print("-p\t\t1Source API Endpoint to query parties by party_id\n\n")
 
+	fmt.Println("--all\t\t-g: read every page of the list")
+	fmt.Println("--limit\t\t-g: read pages up to this number of entities")
+	fmt.Println("--ndjson\t-g: stream the list to stdout as NDJSON, one entity per line, at constant memory")
+	fmt.Println("--page-size\tnumber of entities per page read [default 100]")
+	fmt.Print("--prefetch\tnumber of pages fetched in parallel ahead of the page being read [default 0]\n\n")
+
+	fmt.Println("--since\t\t-g filter: entities on or after a date (YYYY-MM-DD or RFC 3339)")
+	fmt.Println("--before\t-g filter: entities before a date (YYYY-MM-DD or RFC 3339)")
+	fmt.Println("--event-type\t-g events filter: event type, such as CONTRACT_PROPOSED")
+	fmt.Println("--status\t-g filter: status, such as OPEN")
+	fmt.Print("--isin\t\t-g filter: instrument ISIN, --party and --ticker filter -g lists too\n\n")
+
 	fmt.Println("-cp\t\t1Source API Endpoint to PROPOSE a contract from a JSON file")
+	fmt.Println("-cpb\t\t1Source API Endpoint to PROPOSE contracts from a directory of JSON files or an NDJSON file")
 	fmt.Println("-cc\t\t1Source API Endpoint to CANCEL a proposed contract by contract_id")
 	fmt.Println("-ca\t\t1Source API Endpoint to APPROVE a proposed contract by contract_id")
 	fmt.Println("-cd\t\t1Source API Endpoint to DECLINE a proposed contract by contract_id")
+	fmt.Print("-bulk\t\t1Source API Endpoints to CANCEL or DECLINE all PROPOSED contracts matching a filter [cancel, decline]\n\n")
+
+	fmt.Println("mark\t\tmark open contracts to market from a ticker_or_isin,price CSV file and report collateral movements")
+	fmt.Println("accrue\t\tcompute rebate and fee accruals and billing statements for a period [YYYY-MM, YYYY-MM-DD:YYYY-MM-DD]")
+	fmt.Println("exposure\taggregate open contracts by comma separated dimensions [all, counterparty, instrument, currency, venue]")
+	fmt.Println("profiles\tlist the profiles of the configuration TOML file [list]")
+	fmt.Println("config\t\tcheck the configuration TOML file and report every problem found, or create it interactively [check, init]")
+	fmt.Println("snapshot\tread every entity type in parallel into a timestamped snapshot of the local store [take]")
+	fmt.Println("--archive\tsnapshot: pack the snapshot into a .tar.gz archive")
+	fmt.Print("--snapshot-dir\tlocal store of snapshots [default .1source-snapshots]\n\n")
+
+	fmt.Println("Offline commands:")
+	fmt.Println("validate\tcheck a contract proposal JSON file without calling the API")
+	fmt.Println("check-ids\tcheck the CUSIP, ISIN, SEDOL, FIGI, LEI and BIC identifiers of a contract proposal JSON file")
+	fmt.Println("collateral\tcompare the collateral values of a contract proposal JSON file with the computed values")
+	fmt.Println("--fill\t\tprint the contract proposal with missing collateral values filled in")
+	fmt.Println("audit\t\tverify the hash chain of the audit journal, or export it as CSV [verify, export]")
+	fmt.Println("simulate\tserve a local 1Source API and KeyCloak simulator on an address such as :8080")
+	fmt.Print("diff\t\tcompare two snapshots, given as paths, names or dates (YYYY-MM-DD) of the local store\n\n")
+
+	fmt.Print("--dry-run\tprint the request a -cp, -cpb, -cc, -cd or -bulk command would send without calling the API\n\n")
+
+	fmt.Println("--concurrency\tnumber of concurrent calls made by -cpb and -bulk [default 4]")
+	fmt.Println("--results\tresults file written by -cpb [default propose-results.ndjson]")
+	fmt.Print("--resume\tskip -cpb inputs which already succeeded in the results file\n\n")
+
+	fmt.Println("--party\t\t-g and -bulk filter on the partyId of a transacting party")
+	fmt.Println("--ticker\t-g and -bulk filter on the instrument ticker")
+	fmt.Println("--trade-date\t-bulk filter on the trade date (YYYY-MM-DD)")
+	fmt.Println("--venue-ref\t-bulk filter on the execution venue venueRefId")
+	fmt.Print("--yes\t\tdo not ask for confirmation before a -bulk action\n\n")
+
+	fmt.Println("--self\t\tmark, accrue, exposure: your partyId, used to name the counterparty of each contract")
+	fmt.Print("--csv\t\tmark, accrue, exposure, diff: write the report to a CSV file\n\n")
+
+	fmt.Println("--refresh\texposure: fetch contracts from the API even when the local cache holds them")
+	fmt.Println("--cache-dir\tlocal cache directory written by -g [default .1source-cache]")
+	fmt.Print("--audit-file\taudit journal of the calls changing state in the API [default 1source-audit.ndjson]\n\n")
+
+	fmt.Println("--record\twrite every HTTP request and response, with credentials redacted, to a cassette file")
+	fmt.Print("--replay\tanswer every HTTP request from a cassette file instead of the network\n\n")
+
+	fmt.Println("--log-level\tlog level [debug, info, warn, error; default info]")
+	fmt.Println("--log-format\tlog format [text, json; default text]")
+	fmt.Println("--log-output\tlog destination [a file path, stderr, none; default 1source-go.log]")
 	fmt.Println("")
 }
 
-// PrintResults outputs entities fetched from the 1Source REST API to the console
+// PrintResults outputs entities fetched from the 1Source REST API to the
+// console, or the error to stderr
 func PrintResults(err error, data string, prompt string, header string) {
 	if err != nil {
-		fmt.Println(prompt, err)
+		fmt.Fprintln(os.Stderr, prompt, err)
 	} else {
 		fmt.Println(header)
 		fmt.Println(strings.Repeat("=", len(header)))
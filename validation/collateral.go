@@ -0,0 +1,45 @@
+// Package validation checks 1Source contract proposals offline, before
+// they are submitted to the 1Source REST API.
+package validation
+
+import (
+	"github.com/dharm-kapadia/1source-go/collateral"
+	"github.com/dharm-kapadia/1source-go/models"
+)
+
+// checkCollateral checks the contract and collateral values of a proposal
+// against the values derived from price, quantity, margin and rounding
+func checkCollateral(problems *Problems, proposal *models.ContractProposal) {
+	block := proposal.Trade.Collateral
+	terms := collateral.ProposalTerms(proposal)
+
+	if terms.Price == 0 {
+		problems.add("trade.collateral.contractPrice", "is required when trade.instrument.price is not given")
+		return
+	}
+
+	if terms.Margin == 0 {
+		problems.add("trade.collateral.margin", "is required and must be greater than zero")
+		return
+	}
+
+	values, err := collateral.Compute(terms)
+	if err != nil {
+		// Missing or malformed inputs are reported by the other checks
+		return
+	}
+
+	if block.ContractValue == 0 {
+		problems.add("trade.collateral.contractValue", "is required, expected %.2f", values.ContractValue)
+	} else if !collateral.Equal(block.ContractValue, values.ContractValue) {
+		problems.add("trade.collateral.contractValue", "%.2f does not match contractPrice × quantity, expected %.2f",
+			block.ContractValue, values.ContractValue)
+	}
+
+	if block.CollateralValue == 0 {
+		problems.add("trade.collateral.collateralValue", "is required, expected %.2f", values.CollateralValue)
+	} else if !collateral.Equal(block.CollateralValue, values.CollateralValue) {
+		problems.add("trade.collateral.collateralValue", "%.2f does not match the margin and rounding terms, expected %.2f",
+			block.CollateralValue, values.CollateralValue)
+	}
+}
@@ -0,0 +1,77 @@
+// Package validation checks 1Source contract proposals offline, before
+// they are submitted to the 1Source REST API.
+package validation
+
+import (
+	"fmt"
+
+	"github.com/dharm-kapadia/1source-go/identifiers"
+	"github.com/dharm-kapadia/1source-go/models"
+)
+
+// IdentifierCheck is the outcome of validating one identifier found in a
+// contract proposal. Err is nil when the identifier is valid.
+type IdentifierCheck struct {
+	Field string
+	Kind  string
+	Value string
+	Err   error
+}
+
+// CheckIdentifiers validates every security and entity identifier present
+// in a contract proposal. Absent identifiers are skipped. When both an
+// ISIN and a CUSIP are present, they are also checked against each other.
+func CheckIdentifiers(proposal *models.ContractProposal) []IdentifierCheck {
+	var checks []IdentifierCheck
+
+	add := func(field string, kind string, value string) {
+		if value != "" {
+			checks = append(checks, IdentifierCheck{
+				Field: field,
+				Kind:  kind,
+				Value: value,
+				Err:   identifiers.Validate(kind, value),
+			})
+		}
+	}
+
+	trade := proposal.Trade
+	instrument := trade.Instrument
+
+	add("trade.instrument.cusip", identifiers.CUSIP, instrument.Cusip)
+	add("trade.instrument.isin", identifiers.ISIN, instrument.Isin)
+	add("trade.instrument.sedol", identifiers.SEDOL, instrument.Sedol)
+	add("trade.instrument.figi", identifiers.FIGI, instrument.Figi)
+
+	if instrument.Isin != "" && instrument.Cusip != "" {
+		checks = append(checks, IdentifierCheck{
+			Field: "trade.instrument.isin",
+			Kind:  identifiers.ISIN + "/" + identifiers.CUSIP,
+			Value: instrument.Isin + "/" + instrument.Cusip,
+			Err:   identifiers.CheckISINAndCUSIP(instrument.Isin, instrument.Cusip),
+		})
+	}
+
+	add("trade.executionVenue.platform.gleifLei", identifiers.LEI, trade.ExecutionVenue.Platform.GleifLei)
+
+	for i, tp := range trade.TransactingParties {
+		add(fmt.Sprintf("trade.transactingParties[%d].party.gleifLei", i), identifiers.LEI, tp.Party.GleifLei)
+	}
+
+	for i, s := range proposal.Settlement {
+		field := fmt.Sprintf("settlement[%d].instruction", i)
+		add(field+".settlementBic", identifiers.BIC, s.Instruction.SettlementBic)
+		add(field+".localAgentBic", identifiers.BIC, s.Instruction.LocalAgentBic)
+	}
+
+	return checks
+}
+
+// checkIdentifiers records a problem for every invalid identifier
+func checkIdentifiers(problems *Problems, proposal *models.ContractProposal) {
+	for _, check := range CheckIdentifiers(proposal) {
+		if check.Err != nil {
+			problems.add(check.Field, "%s", check.Err)
+		}
+	}
+}
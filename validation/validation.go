@@ -0,0 +1,214 @@
+// Package validation checks 1Source contract proposals offline, before
+// they are submitted to the 1Source REST API.
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dharm-kapadia/1source-go/models"
+)
+
+// DateLayout is the layout of the dates in a contract proposal
+const DateLayout = "2006-01-02"
+
+// Allowed values of the enumerated proposal fields
+var (
+	TermTypes       = []string{"OPEN", "TERM"}
+	SettlementTypes = []string{"DVP", "FOP"}
+	RoundingModes   = []string{"ALWAYSUP", "ALWAYSDOWN"}
+	CollateralTypes = []string{"CASH", "NONCASH", "CASHPOOL", "TRIPARTY"}
+	PartyRoles      = []string{"LENDER", "BORROWER"}
+	VenueTypes      = []string{"ONPLATFORM", "OFFPLATFORM"}
+)
+
+// Problem is one issue found in a contract proposal. Field is the JSON
+// path of the offending value.
+type Problem struct {
+	Field   string
+	Message string
+}
+
+func (p Problem) Error() string {
+	if p.Field == "" {
+		return p.Message
+	}
+
+	return p.Field + ": " + p.Message
+}
+
+// Problems is the list of issues found in a contract proposal
+type Problems []Problem
+
+func (ps Problems) Error() string {
+	messages := make([]string, len(ps))
+	for i, p := range ps {
+		messages[i] = p.Error()
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// add records a problem for field
+func (ps *Problems) add(field string, format string, args ...any) {
+	*ps = append(*ps, Problem{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// ValidateProposal decodes a JSON contract proposal and checks it.
+// It returns nil when no problems are found.
+func ValidateProposal(body []byte) Problems {
+	var proposal models.ContractProposal
+
+	if err := json.Unmarshal(body, &proposal); err != nil {
+		return Problems{{Message: "invalid proposal JSON: " + err.Error()}}
+	}
+
+	return ValidateContractProposal(&proposal)
+}
+
+// ValidateContractProposal checks required fields, enumerated values,
+// dates, collateral values, party consistency and identifiers of a
+// contract proposal. It returns nil when no problems are found.
+func ValidateContractProposal(proposal *models.ContractProposal) Problems {
+	var problems Problems
+	trade := proposal.Trade
+
+	// Execution venue
+	checkEnum(&problems, "trade.executionVenue.type", trade.ExecutionVenue.VenueType, VenueTypes, true)
+	for i, vp := range trade.ExecutionVenue.VenueParties {
+		checkEnum(&problems, fmt.Sprintf("trade.executionVenue.venueParties[%d].partyRole", i), vp.PartyRole, PartyRoles, true)
+	}
+
+	// Instrument
+	instrument := trade.Instrument
+	if instrument.Ticker == "" && instrument.Cusip == "" && instrument.Isin == "" && instrument.Sedol == "" && instrument.Figi == "" {
+		problems.add("trade.instrument", "one of ticker, cusip, isin, sedol or figi is required")
+	}
+
+	// Quantity and currencies
+	if trade.Quantity == 0 {
+		problems.add("trade.quantity", "is required and must be greater than zero")
+	}
+	checkCurrency(&problems, "trade.billingCurrency", trade.BillingCurrency)
+
+	// Term and settlement
+	checkEnum(&problems, "trade.termType", trade.TermType, TermTypes, true)
+	checkEnum(&problems, "trade.settlementType", trade.SettlementType, SettlementTypes, true)
+	checkDates(&problems, trade.TradeDate, trade.SettlementDate, trade.TermType, trade.TermDate)
+
+	// Collateral
+	collateral := trade.Collateral
+	checkCurrency(&problems, "trade.collateral.currency", collateral.Currency)
+	checkEnum(&problems, "trade.collateral.type", collateral.Type, CollateralTypes, true)
+	checkEnum(&problems, "trade.collateral.roundingMode", collateral.RoundingMode, RoundingModes, false)
+	checkCollateral(&problems, proposal)
+
+	// Transacting parties
+	checkParties(&problems, proposal)
+
+	// Settlement instructions
+	for i, s := range proposal.Settlement {
+		checkEnum(&problems, fmt.Sprintf("settlement[%d].partyRole", i), s.PartyRole, PartyRoles, true)
+	}
+
+	// Security and entity identifiers
+	checkIdentifiers(&problems, proposal)
+
+	return problems
+}
+
+// checkEnum checks that value is one of allowed. An empty value is a
+// problem only when the field is required.
+func checkEnum(problems *Problems, field string, value string, allowed []string, required bool) {
+	if value == "" {
+		if required {
+			problems.add(field, "is required")
+		}
+		return
+	}
+
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+
+	problems.add(field, "'%s' is not one of %s", value, strings.Join(allowed, ", "))
+}
+
+// checkCurrency checks that a required currency is an ISO 4217 style code
+func checkCurrency(problems *Problems, field string, currency string) {
+	if currency == "" {
+		problems.add(field, "is required")
+		return
+	}
+
+	if len(currency) != 3 || strings.ToUpper(currency) != currency {
+		problems.add(field, "'%s' is not a three letter currency code", currency)
+	}
+}
+
+// parseDate parses a proposal date, recording a problem when the date is
+// missing and required, or malformed
+func parseDate(problems *Problems, field string, value string, required bool) (time.Time, bool) {
+	if value == "" {
+		if required {
+			problems.add(field, "is required")
+		}
+		return time.Time{}, false
+	}
+
+	date, err := time.Parse(DateLayout, value)
+	if err != nil {
+		problems.add(field, "'%s' is not a date in YYYY-MM-DD format", value)
+		return time.Time{}, false
+	}
+
+	return date, true
+}
+
+// checkDates checks the trade, settlement and term dates against each other
+func checkDates(problems *Problems, tradeDate, settlementDate, termType, termDate string) {
+	trade, tradeOk := parseDate(problems, "trade.tradeDate", tradeDate, true)
+	settlement, settlementOk := parseDate(problems, "trade.settlementDate", settlementDate, true)
+	term, termOk := parseDate(problems, "trade.termDate", termDate, termType == "TERM")
+
+	if tradeOk && settlementOk && settlement.Before(trade) {
+		problems.add("trade.settlementDate", "%s is before tradeDate %s", settlementDate, tradeDate)
+	}
+
+	if termType == "TERM" && termOk && tradeOk && !term.After(trade) {
+		problems.add("trade.termDate", "%s must be after tradeDate %s for a TERM contract", termDate, tradeDate)
+	}
+}
+
+// checkParties checks that the transacting parties hold exactly one
+// LENDER and one BORROWER
+func checkParties(problems *Problems, proposal *models.ContractProposal) {
+	parties := proposal.Trade.TransactingParties
+	if len(parties) == 0 {
+		problems.add("trade.transactingParties", "is required")
+		return
+	}
+
+	counts := make(map[string]int)
+
+	for i, tp := range parties {
+		field := fmt.Sprintf("trade.transactingParties[%d]", i)
+
+		checkEnum(problems, field+".partyRole", tp.PartyRole, PartyRoles, true)
+		counts[tp.PartyRole]++
+
+		if tp.Party.PartyId == "" {
+			problems.add(field+".party.partyId", "is required")
+		}
+	}
+
+	for _, role := range PartyRoles {
+		if counts[role] != 1 {
+			problems.add("trade.transactingParties", "expected exactly one %s, found %d", role, counts[role])
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package validation
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/dharm-kapadia/1source-go/models"
+)
+
+// sampleProposal returns the sample contract proposal of the repository
+func sampleProposal(t *testing.T) []byte {
+	t.Helper()
+
+	body, err := os.ReadFile("../proposed_trade.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func TestValidateSample(t *testing.T) {
+	if problems := ValidateProposal(sampleProposal(t)); problems != nil {
+		t.Errorf("ValidateProposal() of the sample proposal = %v", problems)
+	}
+
+	problems := ValidateProposal([]byte(`{"trade":`))
+	if len(problems) != 1 || problems[0].Field != "" {
+		t.Errorf("ValidateProposal() of invalid JSON = %v", problems)
+	}
+}
+
+func TestValidateContractProposal(t *testing.T) {
+	body := sampleProposal(t)
+
+	tests := []struct {
+		name     string
+		change   func(p *models.ContractProposal)
+		expected []string
+	}{
+		{"collateral value off by one", func(p *models.ContractProposal) {
+			p.Trade.Collateral.CollateralValue = 22610341
+		}, []string{"trade.collateral.collateralValue"}},
+		{"missing contract value", func(p *models.ContractProposal) {
+			p.Trade.Collateral.ContractValue = 0
+		}, []string{"trade.collateral.contractValue"}},
+		{"price from the instrument", func(p *models.ContractProposal) {
+			p.Trade.Collateral.ContractPrice = 0
+		}, nil},
+		{"unknown rounding mode", func(p *models.ContractProposal) {
+			p.Trade.Collateral.RoundingMode = "UP"
+		}, []string{"trade.collateral.roundingMode"}},
+		{"lower case currency", func(p *models.ContractProposal) {
+			p.Trade.BillingCurrency = "usd"
+		}, []string{"trade.billingCurrency"}},
+		{"settlement before trade", func(p *models.ContractProposal) {
+			p.Trade.SettlementDate = "2023-11-14"
+		}, []string{"trade.settlementDate"}},
+		{"term ending on the trade date", func(p *models.ContractProposal) {
+			p.Trade.TermType = "TERM"
+		}, []string{"trade.termDate"}},
+		{"two lenders", func(p *models.ContractProposal) {
+			p.Trade.TransactingParties[0].PartyRole = "LENDER"
+		}, []string{"trade.transactingParties", "trade.transactingParties"}},
+		{"ISIN check digit", func(p *models.ContractProposal) {
+			p.Trade.Instrument.Isin = "US46625H1006"
+		}, []string{"trade.instrument.isin"}},
+		{"ISIN of another CUSIP", func(p *models.ContractProposal) {
+			p.Trade.Instrument.Isin = "US0378331005"
+		}, []string{"trade.instrument.isin"}},
+		{"settlement BIC", func(p *models.ContractProposal) {
+			p.Settlement[0].Instruction.SettlementBic = "EWRE1MV1"
+		}, []string{"settlement[0].instruction.settlementBic"}},
+	}
+
+	for _, tt := range tests {
+		var proposal models.ContractProposal
+		if err := json.Unmarshal(body, &proposal); err != nil {
+			t.Fatal(err)
+		}
+		tt.change(&proposal)
+
+		var fields []string
+		for _, p := range ValidateContractProposal(&proposal) {
+			fields = append(fields, p.Field)
+		}
+		if !reflect.DeepEqual(fields, tt.expected) {
+			t.Errorf("%s: problems in %v, expected %v", tt.name, fields, tt.expected)
+		}
+	}
+}
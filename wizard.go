@@ -0,0 +1,371 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/dharm-kapadia/1source-go/api"
+	"github.com/dharm-kapadia/1source-go/models"
+	"github.com/dharm-kapadia/1source-go/utils"
+)
+
+// environment holds the URLs of a known 1Source environment
+type environment struct {
+	Name      string
+	AuthURL   string
+	RealmName string
+	Base      string
+}
+
+// environments are the 1Source environments offered by the init wizard
+var environments = []environment{
+	{Name: "stage", AuthURL: "https://stageauth.equilend.com/auth", RealmName: "1Source", Base: "https://stageapi.equilend.com"},
+}
+
+// profileName is the syntax of a profile name written by the init wizard
+var profileName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// wizard asks the questions of the init command
+type wizard struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// ask prints a question and returns the answer, or def when the answer
+// is empty
+func (w *wizard) ask(question string, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(w.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(w.out, "%s: ", question)
+	}
+
+	answer, err := w.in.ReadString('\n')
+	if err != nil && (err != io.EOF || answer == "") {
+		return "", fmt.Errorf("no answer to '%s': %w", question, err)
+	}
+
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return def, nil
+	}
+
+	return answer, nil
+}
+
+// required asks a question until it gets a non-empty answer
+func (w *wizard) required(question string, def string) (string, error) {
+	for {
+		answer, err := w.ask(question, def)
+		if err != nil || answer != "" {
+			return answer, err
+		}
+		fmt.Fprintln(w.out, "  a value is required")
+	}
+}
+
+// choose asks for one of the choices
+func (w *wizard) choose(question string, choices []string, def string) (string, error) {
+	for {
+		answer, err := w.ask(fmt.Sprintf("%s (%s)", question, strings.Join(choices, ", ")), def)
+		if err != nil {
+			return "", err
+		}
+
+		for _, c := range choices {
+			if strings.EqualFold(answer, c) {
+				return c, nil
+			}
+		}
+		fmt.Fprintf(w.out, "  expected one of %s\n", strings.Join(choices, ", "))
+	}
+}
+
+// yes asks a yes or no question, no being the default
+func (w *wizard) yes(question string) (bool, error) {
+	answer, err := w.ask(question+" [y/N]", "")
+	answer = strings.ToLower(answer)
+
+	return answer == "y" || answer == "yes", err
+}
+
+// initConfig runs the init wizard, which asks for the environment, the
+// auth settings and where the credentials come from, checks the KeyCloak
+// server and the login as it goes, and writes the configuration to
+// filename. A new file gets the default profile. A named profile is
+// added to an existing file.
+func initConfig(filename string, profile string) error {
+	existing, err := os.ReadFile(filename)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		existing = nil
+	case err != nil:
+		return err
+	case profile == "" || profile == utils.DefaultProfile:
+		return fmt.Errorf("'%s' already exists, use --profile <name> to add a profile to it", filename)
+	}
+
+	if profile == "" {
+		profile = utils.DefaultProfile
+	}
+	if !profileName.MatchString(profile) {
+		return fmt.Errorf("invalid profile name '%s', use letters, digits, '-' and '_'", profile)
+	}
+	if existing != nil {
+		profiles, err := utils.ListProfiles(filename)
+		if err != nil {
+			return err
+		}
+		for _, p := range profiles {
+			if p.Name == profile {
+				return fmt.Errorf("profile '%s' is already defined in '%s'", profile, filename)
+			}
+		}
+	}
+
+	w := &wizard{in: bufio.NewReader(os.Stdin), out: os.Stdout}
+	fmt.Fprintf(w.out, "Creating profile '%s' in '%s'\n\n", profile, filename)
+
+	var cfg models.AppConfig
+	if err := w.askEnvironment(&cfg); err != nil {
+		return err
+	}
+
+	if err := w.askAuthentication(&cfg); err != nil {
+		return err
+	}
+
+	if err := w.checkLogin(cfg); err != nil {
+		return err
+	}
+
+	content := configTOML(cfg, profile)
+	if existing != nil {
+		content = string(existing) + "\n" + content
+	}
+
+	if err := writeConfig(filename, profile, content); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w.out, "\nWrote profile '%s' to '%s'\n", profile, filename)
+	return nil
+}
+
+// askEnvironment asks for the environment, or its URLs and realm, until
+// the KeyCloak server answers for the realm
+func (w *wizard) askEnvironment(cfg *models.AppConfig) error {
+	names := make([]string, 0, len(environments)+1)
+	for _, env := range environments {
+		names = append(names, env.Name)
+	}
+	names = append(names, "custom")
+
+	for {
+		name, err := w.choose("Environment", names, environments[0].Name)
+		if err != nil {
+			return err
+		}
+
+		env := environment{Name: name}
+		for _, e := range environments {
+			if e.Name == name {
+				env = e
+			}
+		}
+
+		if env.AuthURL == "" {
+			if env.AuthURL, err = w.required("KeyCloak auth URL", ""); err != nil {
+				return err
+			}
+			if env.RealmName, err = w.required("Realm name", "1Source"); err != nil {
+				return err
+			}
+			if env.Base, err = w.required("API base URL", ""); err != nil {
+				return err
+			}
+		}
+
+		cfg.General.Auth_URL = env.AuthURL
+		cfg.General.Realm_Name = env.RealmName
+		cfg.Endpoints.Base = env.Base
+		cfg.Endpoints.Api_Version = "v1"
+
+		fmt.Fprintf(w.out, "Checking realm '%s' at %s... ", env.RealmName, env.AuthURL)
+		err = api.CheckRealm(env.AuthURL, env.RealmName)
+		if err == nil {
+			fmt.Fprintln(w.out, "ok")
+			return nil
+		}
+		fmt.Fprintf(w.out, "failed\n  %s\n", err)
+
+		if retry, err := w.yes("Enter the environment again?"); err != nil || !retry {
+			return fmt.Errorf("cannot reach KeyCloak realm '%s' at %s", env.RealmName, env.AuthURL)
+		}
+	}
+}
+
+// askAuthentication asks for the auth settings and where the password
+// and client secret come from, which are set as secret references
+func (w *wizard) askAuthentication(cfg *models.AppConfig) error {
+	var err error
+
+	cfg.Authentication.Auth_Type = "BEARER"
+	cfg.Authentication.Grant_Type = "password"
+
+	if cfg.Authentication.Client_Id, err = w.required("Client id", "canton-participant1-client"); err != nil {
+		return err
+	}
+	if cfg.Authentication.Username, err = w.required("Username", ""); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(w.out, "\nSecrets are never stored in the configuration file. They are read from an")
+	fmt.Fprintln(w.out, "environment variable, a file readable only by you, or a credential helper command.")
+
+	if cfg.Authentication.Password, err = w.askSecret("Password", "ONESOURCE_PASSWORD", true); err != nil {
+		return err
+	}
+
+	cfg.Authentication.Client_Secret, err = w.askSecret("Client secret", "ONESOURCE_CLIENT_SECRET", false)
+	return err
+}
+
+// askSecret asks where a secret comes from and returns its reference
+func (w *wizard) askSecret(name string, envVar string, required bool) (string, error) {
+	sources := []string{"env", "file", "exec"}
+	def := "env"
+	if !required {
+		sources = append(sources, "none")
+	}
+
+	source, err := w.choose(name+" source", sources, def)
+	if err != nil {
+		return "", err
+	}
+
+	switch source {
+	case "env":
+		variable, err := w.required("  environment variable", envVar)
+		return "${" + variable + "}", err
+	case "file":
+		path, err := w.required("  secret file path", "")
+		return "${file:" + path + "}", err
+	case "exec":
+		command, err := w.required("  credential helper command", "")
+		return "${exec:" + command + "}", err
+	}
+
+	return "", nil
+}
+
+// checkLogin logs into KeyCloak with the secret references resolved. When the login
+// fails, the user decides whether to write the configuration anyway.
+func (w *wizard) checkLogin(cfg models.AppConfig) error {
+	fmt.Fprintf(w.out, "\nLogging in as '%s'... ", cfg.Authentication.Username)
+
+	_, err := utils.ResolveSecrets(&cfg)
+	if err == nil {
+		_, err = api.GetAuthToken(&cfg)
+	}
+
+	if err == nil {
+		fmt.Fprintln(w.out, "ok")
+		return nil
+	}
+	fmt.Fprintf(w.out, "failed\n  %s\n", err)
+
+	write, askErr := w.yes("Write the configuration anyway?")
+	if askErr != nil {
+		return askErr
+	}
+	if !write {
+		return fmt.Errorf("login failed, configuration not written")
+	}
+
+	return nil
+}
+
+// configTOML returns the TOML sections of a profile. The default profile
+// uses the top-level sections.
+func configTOML(cfg models.AppConfig, profile string) string {
+	prefix := ""
+	if profile != utils.DefaultProfile {
+		prefix = "profiles." + profile + "."
+	}
+
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "[%sgeneral]\n", prefix)
+	fmt.Fprintf(&sb, "auth_url = %s\n", tomlString(cfg.General.Auth_URL))
+	fmt.Fprintf(&sb, "realm_name = %s\n\n", tomlString(cfg.General.Realm_Name))
+
+	fmt.Fprintf(&sb, "[%sendpoints]\n", prefix)
+	fmt.Fprintf(&sb, "base = %s\n", tomlString(cfg.Endpoints.Base))
+	fmt.Fprintf(&sb, "api_version = %s\n\n", tomlString(cfg.Endpoints.Api_Version))
+
+	fmt.Fprintf(&sb, "[%sauthentication]\n", prefix)
+	fmt.Fprintf(&sb, "auth_type = %s\n", tomlString(cfg.Authentication.Auth_Type))
+	fmt.Fprintf(&sb, "grant_type = %s\n", tomlString(cfg.Authentication.Grant_Type))
+	fmt.Fprintf(&sb, "client_id = %s\n", tomlString(cfg.Authentication.Client_Id))
+	fmt.Fprintf(&sb, "username = %s\n", tomlString(cfg.Authentication.Username))
+	fmt.Fprintf(&sb, "password = %s\n", tomlString(cfg.Authentication.Password))
+	if cfg.Authentication.Client_Secret != "" {
+		fmt.Fprintf(&sb, "client_secret = %s\n", tomlString(cfg.Authentication.Client_Secret))
+	}
+
+	return sb.String()
+}
+
+// tomlString quotes a TOML string value, as a literal string when possible
+func tomlString(value string) string {
+	if strings.ContainsAny(value, "'\n\r") {
+		return fmt.Sprintf("%q", value)
+	}
+
+	return "'" + value + "'"
+}
+
+// writeConfig validates the configuration content and writes it to
+// filename, readable only by its owner. The file is replaced atomically
+// and left untouched when the content is not valid.
+func writeConfig(filename string, profile string, content string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), ".1source-init-*.toml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.WriteString(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	// Secrets which cannot be resolved here may be available where the
+	// configuration is used, so only the other problems are fatal
+	_, errs := utils.CheckConfig(tmp.Name(), profile)
+	var problems utils.ConfigErrors
+	for _, e := range errs {
+		if !strings.HasPrefix(e.Key, "authentication.password") && !strings.HasPrefix(e.Key, "authentication.client_secret") {
+			problems = append(problems, e)
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("generated configuration is not valid:\n%w", problems)
+	}
+
+	return os.Rename(tmp.Name(), filename)
+}